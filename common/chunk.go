@@ -0,0 +1,27 @@
+package common
+
+import (
+	"encoding/json"
+)
+
+// Chunk 任务实时输出片段
+type Chunk struct {
+	ExecID   string `json:"execId"`   // 执行编号
+	TaskName string `json:"taskName"` // 任务名称
+	Attempt  int    `json:"attempt"`  // 第几次执行
+	Seq      int    `json:"seq"`      // 片段序号
+	Stdout   string `json:"stdout"`   // 标准输出
+	Stderr   string `json:"stderr"`   // 标准错误输出
+	EOF      bool   `json:"eof"`      // 是否为最后一个片段
+}
+
+// NewChunk 实例化任务实时输出片段对象
+func NewChunk() *Chunk {
+	return &Chunk{}
+}
+
+// Unmarshal 反序列化任务实时输出片段
+func (c *Chunk) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, c)
+	return err
+}
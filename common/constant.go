@@ -8,11 +8,44 @@ const (
 	// PathKill 杀死任务路径
 	PathKill = "/cron/kill/"
 
+	// PathRun 手动执行任务路径
+	PathRun = "/cron/run/"
+
 	// PathLock 任务分布式锁路径
 	PathLock = "/cron/lock/"
 
 	// PathWorker 服务注册路径
 	PathWorker = "/cron/worker/"
+
+	// PathFire 任务最近调度时间路径
+	PathFire = "/cron/fire/"
+
+	// PathOutput 任务实时输出路径
+	PathOutput = "/cron/output/"
+
+	// PathRunning 正在执行的任务路径
+	PathRunning = "/cron/running/"
+
+	// PathDrain 节点排空路径
+	PathDrain = "/cron/drain/"
+
+	// PathWorkflow 读写工作流路径
+	PathWorkflow = "/cron/workflow/"
+
+	// PathWorkflowRun 工作流执行状态路径
+	PathWorkflowRun = "/cron/workflow_run/"
+
+	// PathClaim 任务调度认领路径
+	PathClaim = "/cron/claim/"
+
+	// PathTail 任务实时输出订阅者路径
+	PathTail = "/cron/tail/"
+)
+
+// 实时输出
+const (
+	// TailTTL 实时输出订阅标记的租约时间，订阅连接断开后过期，单位(s)
+	TailTTL = 10
 )
 
 // 响应状态
@@ -24,6 +57,147 @@ const (
 	StateFailure = "Failure"
 )
 
+// 执行状态
+const (
+	// StatusSuccess 执行成功
+	StatusSuccess = "success"
+
+	// StatusFailure 执行失败
+	StatusFailure = "failure"
+
+	// StatusTimeout 执行超时
+	StatusTimeout = "timeout"
+
+	// StatusSkipped 跳过调度
+	StatusSkipped = "skipped"
+
+	// StatusReplaced 被新的调度替换
+	StatusReplaced = "replaced"
+
+	// StatusPartial 广播执行部分节点成功
+	StatusPartial = "partial"
+
+	// StatusPending 工作流节点等待执行
+	StatusPending = "pending"
+
+	// StatusRunning 工作流或工作流节点正在执行
+	StatusRunning = "running"
+)
+
+// 任务类型
+const (
+	// TaskTypeShell 通过 bash -c 执行 shell 命令
+	TaskTypeShell = "shell"
+
+	// TaskTypeExec 不经过 shell 直接执行命令及参数
+	TaskTypeExec = "exec"
+
+	// TaskTypeHTTP 发送 http 请求，按照响应状态码和响应体判断是否成功
+	TaskTypeHTTP = "http"
+
+	// TaskTypeSQL 通过 database/sql 驱动执行 sql 语句
+	TaskTypeSQL = "sql"
+
+	// TaskTypeGRPC grpc 健康检查
+	TaskTypeGRPC = "grpc"
+)
+
+// 工作流
+const (
+	// EdgeSuccess 上游节点执行成功时执行下游节点（默认）
+	EdgeSuccess = "success"
+
+	// EdgeFailure 上游节点执行失败时执行下游节点
+	EdgeFailure = "failure"
+
+	// EdgeAlways 上游节点结束后总是执行下游节点
+	EdgeAlways = "always"
+
+	// WorkflowRunTTL 工作流执行状态的保留时间，单位(s)
+	WorkflowRunTTL = 7 * 24 * 3600
+
+	// WorkflowDispatchTimeout 工作流任务节点触发后等待 worker 认领的默认超时时间，单位(ms)
+	WorkflowDispatchTimeout = 60 * 1000
+)
+
+// 执行模式
+const (
+	// ModeSingle 每次调度只在一个节点上执行（默认）
+	ModeSingle = "single"
+
+	// ModeBroadcast 每次调度在所有匹配的节点上执行
+	ModeBroadcast = "broadcast"
+)
+
+// 并发策略
+const (
+	// ConcurrencyForbid 任务正在执行时跳过新的调度（默认）
+	ConcurrencyForbid = "forbid"
+
+	// ConcurrencyQueue 任务正在执行时排队等待一次调度
+	ConcurrencyQueue = "queue"
+
+	// ConcurrencyReplace 杀死正在执行的任务并执行新的调度
+	ConcurrencyReplace = "replace"
+
+	// ConcurrencyAllow 允许多个调度并行执行
+	ConcurrencyAllow = "allow"
+)
+
+// 重试退避方式
+const (
+	// BackoffFixed 固定间隔
+	BackoffFixed = "fixed"
+
+	// BackoffExponential 指数退避
+	BackoffExponential = "exponential"
+)
+
+// 错过调度处理策略
+const (
+	// MisfireIgnore 忽略错过的调度（默认）
+	MisfireIgnore = "ignore"
+
+	// MisfireOnce 只补偿最近一次错过的调度
+	MisfireOnce = "once"
+
+	// MisfireAll 补偿所有错过的调度
+	MisfireAll = "all"
+
+	// MisfireDefaultLimit all 策略下默认最多补偿的调度次数
+	MisfireDefaultLimit = 10
+)
+
+// 任务分配策略
+const (
+	// AssignRandom 上锁前随机睡眠后竞争分布式锁（默认）
+	AssignRandom = "random"
+
+	// AssignHash 按照任务名称一致性哈希分配到在线节点
+	AssignHash = "hash"
+
+	// AssignLeastLoaded 分配到正在执行任务数最少的在线节点
+	AssignLeastLoaded = "leastLoaded"
+
+	// ClaimTTL 任务调度认领标记的保留时间，单位(s)
+	ClaimTTL = 60
+)
+
+// 节点选择器操作符
+const (
+	// SelectorIn 标签值在集合中
+	SelectorIn = "In"
+
+	// SelectorNotIn 标签值不在集合中或标签不存在
+	SelectorNotIn = "NotIn"
+
+	// SelectorExists 标签存在
+	SelectorExists = "Exists"
+
+	// SelectorDoesNotExist 标签不存在
+	SelectorDoesNotExist = "DoesNotExist"
+)
+
 // 事件类型
 const (
 	// EventPut 保存类型
@@ -34,4 +208,13 @@ const (
 
 	// EventKill 杀死类型
 	EventKill = 2
+
+	// EventMisfire 错过调度类型
+	EventMisfire = 3
+
+	// EventRun 手动执行类型
+	EventRun = 4
 )
+
+// Version 服务版本，编译时可通过 -ldflags "-X crontab/common.Version=x.y.z" 指定
+var Version = "dev"
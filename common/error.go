@@ -5,5 +5,89 @@ import "errors"
 var (
 	ErrorLockIsOccupied = errors.New("分布式锁已被占用")
 
+	ErrorNotAssigned = errors.New("任务已分配给其他节点")
+
+	ErrorWorkerIsBusy = errors.New("节点已达到最大负载")
+
 	ErrorNoLocalIPFound = errors.New("没有找到本地网卡 IP")
+
+	ErrorInvalidWorkerID = errors.New("节点编号不能为空且不能包含 /")
+
+	ErrorWorkerNotFound = errors.New("节点不存在或已下线")
+
+	ErrorWorkerIsDraining = errors.New("节点正在排空，不再执行新的任务")
+
+	ErrorTaskNotFound = errors.New("任务不存在")
+
+	ErrorTaskTimeout = errors.New("任务执行超时")
+
+	ErrorTaskKilled = errors.New("任务已被强杀")
+
+	ErrorTaskIsRunning = errors.New("任务正在执行，跳过本次调度")
+
+	ErrorTaskIsQueued = errors.New("任务已有排队中的调度，跳过本次调度")
+
+	ErrorTaskReplaced = errors.New("任务被新的调度替换")
+
+	ErrorTooManyParallel = errors.New("任务并行执行数已达上限，跳过本次调度")
+
+	ErrorTaskArgsEmpty = errors.New("exec 类型任务的命令及参数不能为空")
+
+	ErrorTaskTypeUnsupported = errors.New("没有在线节点支持该任务类型")
+
+	ErrorSQLQueryEmpty = errors.New("sql 类型任务的驱动、连接地址和 sql 语句不能为空")
+
+	ErrorGRPCTargetEmpty = errors.New("grpc 类型任务的服务地址不能为空")
+
+	ErrorGRPCNotServing = errors.New("grpc 服务状态不是 SERVING")
+
+	ErrorWorkflowNotFound = errors.New("工作流不存在")
+
+	ErrorWorkflowNameInvalid = errors.New("工作流名称不能为空且不能包含 /")
+
+	ErrorWorkflowNodesEmpty = errors.New("工作流的任务节点不能为空")
+
+	ErrorWorkflowNodeInvalid = errors.New("工作流的任务节点不能为空且不能重复")
+
+	ErrorWorkflowEdgeInvalid = errors.New("工作流的依赖边必须引用已声明的不同节点，执行条件只能是 success, failure, always")
+
+	ErrorWorkflowHasCycle = errors.New("工作流的依赖边不能成环")
+
+	ErrorWorkflowRunExists = errors.New("工作流的本次执行已存在")
+
+	ErrorWorkflowNodeNotDispatched = errors.New("工作流的任务节点超时未被任何 worker 执行")
+
+	ErrorHTTPURLEmpty = errors.New("http 类型任务的请求地址不能为空")
+
+	ErrorHTTPStatus = errors.New("响应状态码不符合预期")
+
+	ErrorHTTPBodyMismatch = errors.New("响应体不匹配")
+
+	ErrorTaskDirNotAbs = errors.New("任务工作目录必须是绝对路径")
+
+	ErrorTaskEnvInvalid = errors.New("环境变量名称只能包含字母、数字和下划线，且不能以数字开头")
+
+	ErrorTaskUIDInvalid = errors.New("不允许以 root 用户或非法的 uid 执行任务")
+
+	ErrorTaskGIDInvalid = errors.New("不允许以 root 用户组或非法的 gid 执行任务，指定 gid 时必须同时指定 uid")
+
+	ErrorTaskParamInvalid = errors.New("任务参数名称只能包含字母、数字和下划线，不能以数字开头且不能重复")
+
+	ErrorTaskParamUnknown = errors.New("任务未声明该参数")
+
+	ErrorTaskParamMissing = errors.New("任务必填参数的值不能为空")
+
+	ErrorTaskTemplateInvalid = errors.New("任务 shell 模板不合法或引用了未声明的参数")
+
+	ErrorCgroupUnavailable = errors.New("cgroup v2 不可用，无法限制任务的内存和进程数")
+
+	ErrorTaskTooHeavy = errors.New("任务权重超过所有可执行节点的最大负载")
+
+	ErrorAllWorkersBusy = errors.New("所有节点均已达到最大负载，跳过本次调度")
+
+	ErrorRunAsDenied = errors.New("worker 不是以 root 用户运行，无法切换任务执行用户")
+
+	ErrorRunAsRootDenied = errors.New("worker 以 root 用户运行时，任务必须指定执行用户或配置非 root 的默认用户")
+
+	ErrorRunAsNotSupported = errors.New("当前系统不支持切换任务执行用户")
 )
@@ -1,9 +1,16 @@
 package common
 
+import (
+	"time"
+)
+
 // Event 监听事件
 type Event struct {
-	Type int   // PUT, DELETE
-	Task *Task // 任务信息
+	Type    int         // PUT, DELETE, KILL, MISFIRE, RUN
+	Task    *Task       // 任务信息
+	Missed  []time.Time // 错过的调度时间
+	Trigger *Trigger    // 手动触发任务信息
+	ExecID  string      // 杀死指定的执行编号，为空表示杀死任务的所有执行
 }
 
 // NewEvent 实例化监听事件对象
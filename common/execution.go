@@ -0,0 +1,27 @@
+package common
+
+import (
+	"encoding/json"
+)
+
+// Execution 正在执行的任务
+type Execution struct {
+	ExecID    string `json:"execId"`    // 执行编号
+	TaskName  string `json:"taskName"`  // 任务名称
+	Worker    string `json:"worker"`    // 执行节点
+	Attempt   int    `json:"attempt"`   // 第几次执行
+	Pid       int    `json:"pid"`       // 进程号
+	PlanTime  int64  `json:"planTime"`  // 理论调度时间
+	StartTime int64  `json:"startTime"` // 开始执行时间
+}
+
+// NewExecution 实例化正在执行的任务对象
+func NewExecution() *Execution {
+	return &Execution{}
+}
+
+// Unmarshal 反序列化正在执行的任务数据
+func (e *Execution) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, e)
+	return err
+}
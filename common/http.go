@@ -0,0 +1,67 @@
+package common
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+)
+
+// HTTPRequest http 类型任务的请求配置
+type HTTPRequest struct {
+	Method       string            `json:"method"`       // 请求方法，为空表示 GET
+	URL          string            `json:"url"`          // 请求地址
+	Headers      map[string]string `json:"headers"`      // 请求头
+	Body         string            `json:"body"`         // 请求体
+	ExpectStatus []int             `json:"expectStatus"` // 期望的响应状态码，为空表示 2xx
+	BodyMatch    string            `json:"bodyMatch"`    // 响应体需要匹配的正则表达式，为空表示不校验
+	Timeout      int64             `json:"timeout"`      // 请求超时时间，包含读取响应体，单位(ms)，0 表示使用 worker 的默认超时时间
+}
+
+// GetMethod 获取请求方法
+func (h *HTTPRequest) GetMethod() string {
+	if h.Method == "" {
+		return http.MethodGet
+	}
+	return h.Method
+}
+
+// Validate 校验请求配置
+func (h *HTTPRequest) Validate() error {
+	if h == nil || h.URL == "" {
+		return ErrorHTTPURLEmpty
+	}
+	if _, err := regexp.Compile(h.BodyMatch); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Check 根据响应状态码和响应体判断请求是否成功
+func (h *HTTPRequest) Check(statusCode int, body []byte) error {
+	// 校验响应状态码
+	expected := statusCode >= 200 && statusCode < 300
+	if len(h.ExpectStatus) != 0 {
+		expected = false
+		for _, status := range h.ExpectStatus {
+			if status == statusCode {
+				expected = true
+				break
+			}
+		}
+	}
+	if !expected {
+		return fmt.Errorf("%w: %d", ErrorHTTPStatus, statusCode)
+	}
+
+	// 校验响应体
+	if h.BodyMatch != "" {
+		pattern, err := regexp.Compile(h.BodyMatch)
+		if err != nil {
+			return err
+		}
+		if !pattern.Match(body) {
+			return ErrorHTTPBodyMismatch
+		}
+	}
+	return nil
+}
@@ -0,0 +1,15 @@
+package common
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// NewID 生成唯一编号: 毫秒时间戳 + 随机数
+func NewID() string {
+	buf := make([]byte, 4)
+	_, _ = rand.Read(buf)
+	return strconv.FormatInt(time.Now().UnixNano()/1000/1000, 36) + hex.EncodeToString(buf)
+}
@@ -0,0 +1,9 @@
+package common
+
+// Limits 任务进程的资源限制，0 表示不限制
+type Limits struct {
+	Memory    int64 `json:"memory"`    // 内存上限，单位(byte)，需要 worker 配置可写的 cgroup v2
+	CPUTime   int64 `json:"cpuTime"`   // CPU 时间上限，单位(s)，每个进程单独计算
+	OpenFiles int64 `json:"openFiles"` // 打开文件数上限，每个进程单独计算
+	Processes int64 `json:"processes"` // 进程数上限，需要 worker 配置可写的 cgroup v2
+}
@@ -1,15 +1,37 @@
 package common
 
+import (
+	"strconv"
+)
+
 // Log 任务执行日志
 type Log struct {
-	TaskName  string `json:"taskName" bson:"taskName"`   // 任务名称
-	Command   string `json:"command" bson:"command"`     // 脚本命令
-	Output    string `json:"output" bson:"output"`       // 执行结果
-	Error     string `json:"error" bson:"error"`         // 执行错误
-	PlanTime  int64  `json:"planTime" bson:"planTime"`   // 理论调度时间
-	RealTime  int64  `json:"realTime" bson:"realTime"`   // 实际调度时间
-	StartTime int64  `json:"startTime" bson:"startTime"` // 开始执行时间
-	EndTime   int64  `json:"endTime" bson:"endTime"`     // 结束执行时间
+	ExecID     string `json:"execId" bson:"execId"`         // 执行编号，同一次调度的多次重试共用
+	TaskName   string `json:"taskName" bson:"taskName"`     // 任务名称
+	Command    string `json:"command" bson:"command"`       // 脚本命令
+	Worker     string `json:"worker" bson:"worker"`         // 执行节点
+	Stdout     string `json:"stdout" bson:"stdout"`         // 标准输出
+	Stderr     string `json:"stderr" bson:"stderr"`         // 标准错误输出
+	Output     string `json:"output" bson:"output"`         // 执行结果，已废弃，旧版本 worker 记录的日志只包含该字段
+	Truncated  bool   `json:"truncated" bson:"truncated"`   // 输出是否被截断
+	OutputFile string `json:"outputFile" bson:"outputFile"` // 完整输出在 worker 节点上的溢出文件路径
+	Error      string `json:"error" bson:"error"`           // 执行错误
+	ExitCode   int    `json:"exitCode" bson:"exitCode"`     // 退出码，未正常退出时为 -1
+	Signal     string `json:"signal" bson:"signal"`         // 终止进程的信号
+	Killed     bool   `json:"killed" bson:"killed"`         // 是否被强杀、替换或超时终止
+	MaxRSS     int64  `json:"maxRSS" bson:"maxRSS"`         // 内存使用峰值，单位(byte)
+	CPUTime    int64  `json:"cpuTime" bson:"cpuTime"`       // CPU 时间，单位(ms)
+	StatusCode int    `json:"statusCode" bson:"statusCode"` // http 响应状态码
+	Latency    int64  `json:"latency" bson:"latency"`       // http 请求耗时，单位(ms)
+	Status     string `json:"status" bson:"status"`         // 执行状态: success, failure, timeout, skipped, replaced
+	Attempt    int    `json:"attempt" bson:"attempt"`       // 第几次执行
+	CatchUp    bool   `json:"catchUp" bson:"catchUp"`       // 是否为补偿错过的调度
+	Manual     bool   `json:"manual" bson:"manual"`         // 是否为手动执行
+	User       string `json:"user" bson:"user"`             // 手动执行的触发用户
+	PlanTime   int64  `json:"planTime" bson:"planTime"`     // 理论调度时间
+	RealTime   int64  `json:"realTime" bson:"realTime"`     // 实际调度时间
+	StartTime  int64  `json:"startTime" bson:"startTime"`   // 开始执行时间
+	EndTime    int64  `json:"endTime" bson:"endTime"`       // 结束执行时间
 }
 
 // NewLog 实例化任务执行日志对象
@@ -17,9 +39,103 @@ func NewLog() *Log {
 	return &Log{}
 }
 
+// Build 根据任务执行结果构建任务执行日志对象
+func (l *Log) Build(result *Result) {
+	l.ExecID = result.State.ID
+	l.TaskName = result.State.Task.Name
+	l.Command = result.Command
+	if l.Command == "" {
+		l.Command = result.State.Task.CommandLine()
+	}
+	l.Worker = result.Worker
+	l.Stdout = string(result.Stdout)
+	l.Stderr = string(result.Stderr)
+	l.Truncated = result.Truncated
+	l.OutputFile = result.OutputFile
+	l.ExitCode = result.ExitCode
+	l.Signal = result.Signal
+	l.Killed = result.Killed
+	l.MaxRSS = result.MaxRSS
+	l.CPUTime = result.CPUTime
+	l.StatusCode = result.StatusCode
+	l.Latency = result.Latency
+	l.Status = result.Status
+	l.Attempt = result.Attempt
+	l.CatchUp = result.State.CatchUp
+	if result.State.Trigger != nil {
+		l.Manual = true
+		l.User = result.State.Trigger.User
+	}
+	l.PlanTime = result.State.PlanTime.UnixNano() / 1000 / 1000
+	l.RealTime = result.State.RealTime.UnixNano() / 1000 / 1000
+	l.StartTime = result.StartTime.UnixNano() / 1000 / 1000
+	l.EndTime = result.EndTime.UnixNano() / 1000 / 1000
+	if result.Error != nil {
+		l.Error = result.Error.Error()
+	}
+}
+
+// LogGroup 同一次调度的任务执行日志分组，用于汇总广播执行的各节点结果
+type LogGroup struct {
+	TaskName string `json:"taskName"` // 任务名称
+	PlanTime int64  `json:"planTime"` // 理论调度时间
+	Status   string `json:"status"`   // 汇总状态: success, partial, failure
+	Total    int    `json:"total"`    // 执行节点数
+	Success  int    `json:"success"`  // 执行成功的节点数
+	Logs     []*Log `json:"logs"`     // 各节点的任务执行日志
+}
+
+// GroupLogs 按照调度将任务执行日志分组，各节点以最后一次执行的状态汇总
+func GroupLogs(logs []*Log) []*LogGroup {
+	groupList := make([]*LogGroup, 0)
+	groupTable := make(map[string]*LogGroup)
+	finalTable := make(map[*LogGroup]map[string]*Log) // 各节点最后一次执行的日志
+	for _, log := range logs {
+		// 获取日志所属分组
+		key := log.TaskName + "/" + strconv.FormatInt(log.PlanTime, 10)
+		group, ok := groupTable[key]
+		if !ok {
+			group = &LogGroup{TaskName: log.TaskName, PlanTime: log.PlanTime}
+			groupTable[key] = group
+			groupList = append(groupList, group)
+			finalTable[group] = make(map[string]*Log)
+		}
+		group.Logs = append(group.Logs, log)
+
+		// 记录节点最后一次执行的日志
+		node := log.Worker + "/" + log.ExecID
+		if final, ok := finalTable[group][node]; !ok || final.Attempt < log.Attempt {
+			finalTable[group][node] = log
+		}
+	}
+
+	// 汇总各节点最后一次执行的状态
+	for _, group := range groupList {
+		for _, log := range finalTable[group] {
+			group.Total++
+			if log.Status == StatusSuccess {
+				group.Success++
+			}
+		}
+		switch {
+		case group.Success == group.Total:
+			group.Status = StatusSuccess
+		case group.Success == 0:
+			group.Status = StatusFailure
+		default:
+			group.Status = StatusPartial
+		}
+	}
+
+	return groupList
+}
+
 // LogFilter 任务执行日志过滤条件
 type LogFilter struct {
-	TaskName string `bson:"taskName"`
+	TaskName string `bson:"taskName,omitempty"` // 为空表示不过滤
+	Worker   string `bson:"worker,omitempty"`   // 为空表示不过滤
+	Status   string `bson:"status,omitempty"`   // 为空表示不过滤
+	ExitCode *int   `bson:"exitCode,omitempty"` // 为空表示不过滤
 }
 
 // NewLogFilter 实例化任务执行日志过滤条件对象
@@ -0,0 +1,49 @@
+package common
+
+import (
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+// Misfire 错过调度处理策略
+type Misfire struct {
+	Policy string `json:"policy"` // 处理策略: ignore(默认), once, all
+	Limit  int    `json:"limit"`  // all 策略下最多补偿的调度次数，0 表示使用默认值
+	Grace  int64  `json:"grace"`  // 宽限窗口，超出窗口的调度不再补偿，单位(ms)，0 表示不限制
+}
+
+// Missed 计算 (last, until) 区间内需要补偿的调度时间
+func (m *Misfire) Missed(expr *cronexpr.Expression, last time.Time, until time.Time) []time.Time {
+	// 未配置策略或忽略错过的调度
+	if m == nil || (m.Policy != MisfireOnce && m.Policy != MisfireAll) {
+		return nil
+	}
+
+	// 计算最多补偿的调度次数
+	limit := 1
+	if m.Policy == MisfireAll {
+		limit = m.Limit
+		if limit <= 0 {
+			limit = MisfireDefaultLimit
+		}
+	}
+
+	// 超出宽限窗口的调度不再补偿，宽限窗口以本次认领的调度时间为准
+	from := last
+	if m.Grace > 0 {
+		if start := until.Add(-time.Duration(m.Grace) * time.Millisecond); start.After(from) {
+			from = start
+		}
+	}
+
+	// 保留最近的 limit 次调度时间
+	missed := make([]time.Time, 0, limit)
+	for t := expr.Next(from); !t.IsZero() && t.Before(until); t = expr.Next(t) {
+		if len(missed) == limit {
+			missed = missed[1:]
+		}
+		missed = append(missed, t)
+	}
+	return missed
+}
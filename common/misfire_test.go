@@ -0,0 +1,109 @@
+package common
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+func TestMisfireMissed(t *testing.T) {
+	expr := cronexpr.MustParse("0 * * * * * *") // 每分钟
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	minute := func(n int) time.Time {
+		return base.Add(time.Duration(n) * time.Minute)
+	}
+
+	tests := []struct {
+		name    string
+		misfire *Misfire
+		last    time.Time
+		until   time.Time
+		want    []time.Time
+	}{
+		{
+			name:    "未配置策略",
+			misfire: nil,
+			last:    minute(0),
+			until:   minute(5),
+			want:    nil,
+		},
+		{
+			name:    "忽略错过的调度",
+			misfire: &Misfire{Policy: MisfireIgnore},
+			last:    minute(0),
+			until:   minute(5),
+			want:    nil,
+		},
+		{
+			name:    "没有错过的调度",
+			misfire: &Misfire{Policy: MisfireAll},
+			last:    minute(4),
+			until:   minute(5),
+			want:    []time.Time{},
+		},
+		{
+			name:    "只补偿最近一次",
+			misfire: &Misfire{Policy: MisfireOnce},
+			last:    minute(0),
+			until:   minute(5),
+			want:    []time.Time{minute(4)},
+		},
+		{
+			name:    "补偿所有错过的调度，不包含 until",
+			misfire: &Misfire{Policy: MisfireAll},
+			last:    minute(0),
+			until:   minute(5),
+			want:    []time.Time{minute(1), minute(2), minute(3), minute(4)},
+		},
+		{
+			name:    "保留最近的 limit 次",
+			misfire: &Misfire{Policy: MisfireAll, Limit: 2},
+			last:    minute(0),
+			until:   minute(5),
+			want:    []time.Time{minute(3), minute(4)},
+		},
+		{
+			name:    "默认 limit",
+			misfire: &Misfire{Policy: MisfireAll},
+			last:    minute(0),
+			until:   minute(20),
+			want: []time.Time{minute(10), minute(11), minute(12), minute(13), minute(14),
+				minute(15), minute(16), minute(17), minute(18), minute(19)},
+		},
+		{
+			name:    "宽限窗口以 until 为准",
+			misfire: &Misfire{Policy: MisfireAll, Grace: int64(150 * time.Second / time.Millisecond)},
+			last:    minute(0),
+			until:   minute(5),
+			want:    []time.Time{minute(3), minute(4)},
+		},
+		{
+			name:    "宽限窗口大于错过的区间",
+			misfire: &Misfire{Policy: MisfireAll, Grace: int64(time.Hour / time.Millisecond)},
+			last:    minute(2),
+			until:   minute(5),
+			want:    []time.Time{minute(3), minute(4)},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.misfire.Missed(expr, tt.last, tt.until)
+			if tt.want == nil {
+				if got != nil {
+					t.Fatalf("Missed() = %v, want nil", got)
+				}
+				return
+			}
+			if len(got) != len(tt.want) {
+				t.Fatalf("Missed() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if !got[i].Equal(tt.want[i]) {
+					t.Fatalf("Missed() = %v, want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Param 任务参数
+type Param struct {
+	Name        string `json:"name"`        // 参数名称，在 shell 模板中通过 {{.Params.name}} 引用转义后的值，通过 {{.RawParams.name}} 引用原始值
+	Default     string `json:"default"`     // 默认值
+	Required    bool   `json:"required"`    // 是否必填，必填参数的值不能为空，配置了 cron 表达式的任务必须为必填参数设置默认值
+	Description string `json:"description"` // 参数说明
+}
+
+// TemplateVars shell 模板变量
+type TemplateVars struct {
+	TaskName  string            // 任务名称
+	ExecID    string            // 执行编号
+	WorkerID  string            // 执行节点
+	PlanTime  time.Time         // 理论调度时间，可通过 {{.PlanTime.Format "20060102"}} 格式化
+	RealTime  time.Time         // 实际调度时间
+	Attempt   int               // 第几次执行
+	Params    map[string]string // 任务参数，值已转义为 shell 单引号字符串，可以直接作为命令参数使用
+	RawParams map[string]string // 未转义的任务参数，值会被 shell 解释，只能引用可信的参数
+}
+
+// NewTemplateVars 根据任务执行状态构建 shell 模板变量，手动执行时使用触发信息中的参数覆盖默认值
+func NewTemplateVars(state *State, worker string, attempt int) (*TemplateVars, error) {
+	var overrides map[string]string
+	if state.Trigger != nil {
+		overrides = state.Trigger.Params
+	}
+	params, err := state.Task.ResolveParams(overrides)
+	if err != nil {
+		return nil, err
+	}
+	vars := newParamVars(params)
+	vars.TaskName = state.Task.Name
+	vars.ExecID = state.ID
+	vars.WorkerID = worker
+	vars.PlanTime = state.PlanTime
+	vars.RealTime = state.RealTime
+	vars.Attempt = attempt
+	return vars, nil
+}
+
+// newParamVars 构建只包含任务参数的 shell 模板变量，参数值默认转义，防止手动执行时传入的值被 shell 解释
+func newParamVars(params map[string]string) *TemplateVars {
+	vars := &TemplateVars{
+		Params:    make(map[string]string, len(params)),
+		RawParams: params,
+	}
+	for name, value := range params {
+		vars.Params[name] = shellQuote(value)
+	}
+	return vars
+}
+
+// shellQuote 将字符串转义为 shell 单引号字符串
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// ResolveParams 合并任务参数的默认值和覆盖值，覆盖值只能包含已声明的参数
+func (t *Task) ResolveParams(overrides map[string]string) (map[string]string, error) {
+	params := make(map[string]string, len(t.Params))
+	for _, param := range t.Params {
+		params[param.Name] = param.Default
+	}
+	for name, value := range overrides {
+		if _, ok := params[name]; !ok {
+			return nil, ErrorTaskParamUnknown
+		}
+		params[name] = value
+	}
+	for _, param := range t.Params {
+		if param.Required && params[param.Name] == "" {
+			return nil, ErrorTaskParamMissing
+		}
+	}
+	return params, nil
+}
+
+// Render 渲染 shell 模板，引用未声明的参数时返回错误，未开启模板时原样返回 shell 命令
+func (t *Task) Render(vars *TemplateVars) (string, error) {
+	if !t.IsTemplate() {
+		return t.Shell, nil
+	}
+	tmpl, err := t.parseShell()
+	if err != nil {
+		return "", err
+	}
+	builder := &strings.Builder{}
+	if err := tmpl.Execute(builder, vars); err != nil {
+		return "", ErrorTaskTemplateInvalid
+	}
+	return builder.String(), nil
+}
+
+// validateParams 校验任务参数声明和 shell 模板，使用参数默认值试渲染一次
+func (t *Task) validateParams() error {
+	names := make(map[string]bool, len(t.Params))
+	for _, param := range t.Params {
+		if param == nil || !envNamePattern.MatchString(param.Name) || names[param.Name] {
+			return ErrorTaskParamInvalid
+		}
+		names[param.Name] = true
+
+		// cron 调度不传入参数，必填参数没有默认值时每次调度都会失败
+		if param.Required && param.Default == "" && t.CronExpr != "" {
+			return ErrorTaskParamMissing
+		}
+	}
+
+	// 只有 shell 类型支持模板
+	if t.GetType() != TaskTypeShell || !t.IsTemplate() {
+		return nil
+	}
+	params := make(map[string]string, len(t.Params))
+	for _, param := range t.Params {
+		params[param.Name] = param.Default
+	}
+	_, err := t.Render(newParamVars(params))
+	return err
+}
+
+// parseShell 解析 shell 模板
+func (t *Task) parseShell() (*template.Template, error) {
+	tmpl, err := template.New(t.Name).Option("missingkey=error").Parse(t.Shell)
+	if err != nil {
+		return nil, ErrorTaskTemplateInvalid
+	}
+	return tmpl, nil
+}
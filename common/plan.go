@@ -9,8 +9,10 @@ import (
 // Plan 任务调度计划
 type Plan struct {
 	Task     *Task                // 任务信息
-	Expr     *cronexpr.Expression // 解析后的 cron 表达式
+	Expr     *cronexpr.Expression // 解析后的 cron 表达式，为空表示只能手动或由工作流触发
 	NextTime time.Time            // 下次调度时间
+	Missed   []time.Time          // 等待补偿的调度时间
+	Trigger  *Trigger             // 手动触发任务信息，为空表示 cron 调度
 }
 
 // NewPlan 实例化任务调度计划对象
@@ -20,14 +22,19 @@ func NewPlan() *Plan {
 
 // Build 构造任务调度计划对象
 func (p *Plan) Build(task *Task) error {
+	// 任务调度计划对象赋值
+	p.Task = task
+
+	// 未配置 cron 表达式的任务不参与 cron 调度
+	if task.CronExpr == "" {
+		return nil
+	}
+
 	// 解析 cron 表达式
 	expr, err := cronexpr.Parse(task.CronExpr)
 	if err != nil {
 		return err
 	}
-
-	// 任务调度计划对象赋值
-	p.Task = task
 	p.Expr = expr
 	p.NextTime = expr.Next(time.Now())
 
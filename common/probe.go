@@ -0,0 +1,31 @@
+package common
+
+// SQLQuery sql 类型任务的执行配置
+type SQLQuery struct {
+	Driver string `json:"driver"` // database/sql 驱动名称，如 mysql, postgres
+	DSN    string `json:"dsn"`    // 数据库连接地址
+	Query  string `json:"query"`  // 执行的 sql 语句
+}
+
+// Validate 校验 sql 执行配置
+func (s *SQLQuery) Validate() error {
+	if s == nil || s.Driver == "" || s.DSN == "" || s.Query == "" {
+		return ErrorSQLQueryEmpty
+	}
+	return nil
+}
+
+// GRPCProbe grpc 类型任务的健康检查配置
+type GRPCProbe struct {
+	Target  string `json:"target"`  // grpc 服务地址
+	Service string `json:"service"` // 检查的服务名称，为空表示检查整个服务
+	TLS     bool   `json:"tls"`     // 是否使用 tls 连接
+}
+
+// Validate 校验健康检查配置
+func (g *GRPCProbe) Validate() error {
+	if g == nil || g.Target == "" {
+		return ErrorGRPCTargetEmpty
+	}
+	return nil
+}
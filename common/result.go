@@ -6,11 +6,26 @@ import (
 
 // Result 任务执行结果
 type Result struct {
-	State     *State    // 任务信息
-	Output    []byte    // 执行结果
-	Error     error     // 执行错误
-	StartTime time.Time // 开始执行时间
-	EndTime   time.Time // 结束执行时间
+	State      *State    // 任务信息
+	Worker     string    // 执行节点
+	Command    string    // 渲染后实际执行的命令，为空表示任务配置的命令
+	Stdout     []byte    // 标准输出
+	Stderr     []byte    // 标准错误输出
+	Truncated  bool      // 输出是否被截断
+	OutputFile string    // 完整输出的溢出文件路径
+	Error      error     // 执行错误
+	Status     string    // 执行状态
+	ExitCode   int       // 退出码
+	Signal     string    // 终止进程的信号
+	Killed     bool      // 是否被强杀、替换或超时终止
+	MaxRSS     int64     // 内存使用峰值，单位(byte)
+	CPUTime    int64     // 用户态和内核态 CPU 时间之和，单位(ms)
+	StatusCode int       // http 响应状态码
+	Latency    int64     // http 请求耗时，单位(ms)
+	Attempt    int       // 第几次执行
+	Retrying   bool      // 失败后是否还将重试
+	StartTime  time.Time // 开始执行时间
+	EndTime    time.Time // 结束执行时间
 }
 
 // NewResult 实例化任务执行结果对象
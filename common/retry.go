@@ -0,0 +1,53 @@
+package common
+
+import (
+	"time"
+)
+
+// Retry 任务重试策略
+type Retry struct {
+	MaxAttempts int    `json:"maxAttempts"` // 最大执行次数（含首次执行），小于等于 1 表示不重试
+	Backoff     string `json:"backoff"`     // 退避方式: fixed, exponential
+	Interval    int64  `json:"interval"`    // 重试间隔，单位(ms)
+	MaxInterval int64  `json:"maxInterval"` // 指数退避的最大重试间隔，单位(ms)，0 表示不限制
+	RetryOn     []int  `json:"retryOn"`     // 需要重试的退出码，为空表示任意失败均重试
+}
+
+// Allow 判断第 attempt 次执行失败后是否允许重试
+func (r *Retry) Allow(attempt int, exitCode int) bool {
+	// 未配置重试策略或已达到最大执行次数
+	if r == nil || attempt >= r.MaxAttempts {
+		return false
+	}
+
+	// 未限定退出码，任意失败均重试
+	if len(r.RetryOn) == 0 {
+		return true
+	}
+
+	// 判断退出码是否需要重试
+	for _, code := range r.RetryOn {
+		if code == exitCode {
+			return true
+		}
+	}
+	return false
+}
+
+// Delay 计算第 attempt 次执行失败后的重试间隔
+func (r *Retry) Delay(attempt int) time.Duration {
+	interval := time.Duration(r.Interval) * time.Millisecond
+	if r.Backoff != BackoffExponential {
+		return interval
+	}
+
+	// 指数退避: interval * 2^(attempt-1)，并限制最大重试间隔
+	maxInterval := time.Duration(r.MaxInterval) * time.Millisecond
+	for i := 1; i < attempt && interval < 1<<62; i++ {
+		interval *= 2
+	}
+	if maxInterval > 0 && interval > maxInterval {
+		return maxInterval
+	}
+	return interval
+}
@@ -0,0 +1,63 @@
+package common
+
+// Selector 任务节点选择器，所有条件均满足的节点才能执行任务
+type Selector struct {
+	MatchLabels      map[string]string `json:"matchLabels"`      // 标签相等条件
+	MatchExpressions []*Requirement    `json:"matchExpressions"` // 标签集合条件
+}
+
+// Requirement 标签集合条件
+type Requirement struct {
+	Key      string   `json:"key"`      // 标签名
+	Operator string   `json:"operator"` // 操作符: In, NotIn, Exists, DoesNotExist
+	Values   []string `json:"values"`   // 标签值集合
+}
+
+// Matches 判断节点标签是否满足选择器，未配置选择器时任意节点均满足
+func (s *Selector) Matches(labels map[string]string) bool {
+	if s == nil {
+		return true
+	}
+
+	// 判断标签相等条件
+	for key, value := range s.MatchLabels {
+		if v, ok := labels[key]; !ok || v != value {
+			return false
+		}
+	}
+
+	// 判断标签集合条件
+	for _, requirement := range s.MatchExpressions {
+		if !requirement.Matches(labels) {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches 判断节点标签是否满足标签集合条件
+func (r *Requirement) Matches(labels map[string]string) bool {
+	value, ok := labels[r.Key]
+	switch r.Operator {
+	case SelectorIn:
+		return ok && r.contains(value)
+	case SelectorNotIn:
+		return !ok || !r.contains(value)
+	case SelectorExists:
+		return ok
+	case SelectorDoesNotExist:
+		return !ok
+	default: // 不支持的操作符
+		return false
+	}
+}
+
+// contains 判断标签值集合是否包含 value
+func (r *Requirement) contains(value string) bool {
+	for _, v := range r.Values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
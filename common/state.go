@@ -2,16 +2,22 @@ package common
 
 import (
 	"context"
+	"sync/atomic"
 	"time"
 )
 
 // State 任务执行状态
 type State struct {
+	ID         string             // 执行编号
 	Task       *Task              // 任务信息
 	PlanTime   time.Time          // 理论调度时间
 	RealTime   time.Time          // 实际调度时间
 	CancelCtx  context.Context    // 任务 command 的上下文
 	CancelFunc context.CancelFunc // 取消任务 command 执行的函数
+	Replaced   bool               // 是否被新的调度替换
+	CatchUp    bool               // 是否为补偿错过的调度
+	Trigger    *Trigger           // 手动触发任务信息，为空表示 cron 调度
+	Waiting    atomic.Bool        // 是否正在等待其他节点认领本次调度，等待中的调度不视为正在执行
 }
 
 // NewState 实例化任务执行状态对象
@@ -21,8 +27,10 @@ func NewState() *State {
 
 // Build 构建任务执行状态对象
 func (e *State) Build(plan *Plan) {
+	e.ID = NewID()
 	e.Task = plan.Task
 	e.PlanTime = plan.NextTime
+	e.Trigger = plan.Trigger
 	e.RealTime = time.Now()
 	e.CancelCtx, e.CancelFunc = context.WithCancel(context.TODO())
 }
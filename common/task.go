@@ -2,14 +2,44 @@ package common
 
 import (
 	"encoding/json"
+	"path"
+	"path/filepath"
+	"regexp"
 	"strings"
 )
 
+// envNamePattern 环境变量名称格式
+var envNamePattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
 // Task 任务
 type Task struct {
-	Name     string `json:"name"`     // 任务名称
-	Shell    string `json:"shell"`    // shell 命令
-	CronExpr string `json:"cronExpr"` // cron 表达式
+	Name        string            `json:"name"`        // 任务名称
+	Type        string            `json:"type"`        // 任务类型: shell(默认), exec, http, sql, grpc，worker 可注册自定义类型
+	Shell       string            `json:"shell"`       // shell 命令，shell 类型使用
+	Template    bool              `json:"template"`    // shell 命令是否为 text/template 模板，声明了任务参数时总是作为模板，执行前由 worker 渲染，参数值默认转义为 shell 单引号字符串
+	Params      []*Param          `json:"params"`      // 任务参数声明，手动执行时可覆盖默认值
+	Args        []string          `json:"args"`        // 命令及参数，exec 类型使用，不经过 shell 直接执行
+	Stdin       string            `json:"stdin"`       // 标准输入内容，exec 类型使用
+	HTTP        *HTTPRequest      `json:"http"`        // 请求配置，http 类型使用
+	SQL         *SQLQuery         `json:"sql"`         // sql 执行配置，sql 类型使用
+	GRPC        *GRPCProbe        `json:"grpc"`        // 健康检查配置，grpc 类型使用
+	Dir         string            `json:"dir"`         // 工作目录，必须是绝对路径，为空表示 worker 的工作目录
+	Env         map[string]string `json:"env"`         // 追加的环境变量
+	UID         *int              `json:"uid"`         // 执行用户 uid，为空表示 worker 的用户，worker 以 root 用户运行时才能切换
+	GID         *int              `json:"gid"`         // 执行用户 gid，为空表示与 uid 相同
+	CronExpr    string            `json:"cronExpr"`    // cron 表达式
+	Timeout     int64             `json:"timeout"`     // 执行超时时间，单位(ms)，0 表示不限制
+	KillGrace   int64             `json:"killGrace"`   // 终止任务时发送 SIGTERM 后等待进程退出的宽限期，超时后发送 SIGKILL，单位(ms)，0 表示 5000
+	Retry       *Retry            `json:"retry"`       // 重试策略
+	Concurrency string            `json:"concurrency"` // 并发策略: forbid(默认), queue, replace, allow
+	MaxParallel int               `json:"maxParallel"` // allow 策略下集群内最大并行执行数，0 表示不限制
+	Misfire     *Misfire          `json:"misfire"`     // 错过调度处理策略
+	Disabled    bool              `json:"disabled"`    // 是否已暂停调度
+	OutputLimit int               `json:"outputLimit"` // 标准输出和标准错误输出各自的捕获上限，单位(byte)，0 表示使用 worker 配置
+	Selector    *Selector         `json:"selector"`    // 节点选择器，只有标签匹配的节点才能执行任务
+	Mode        string            `json:"mode"`        // 执行模式: single(默认), broadcast
+	Weight      int               `json:"weight"`      // 任务权重，计入 worker 节点负载，0 表示 1
+	Limits      *Limits           `json:"limits"`      // 资源限制
 }
 
 // NewTask 实例化任务对象
@@ -17,6 +47,100 @@ func NewTask() *Task {
 	return &Task{}
 }
 
+// GetType 获取任务类型
+func (t *Task) GetType() string {
+	if t.Type == "" {
+		return TaskTypeShell
+	}
+	return t.Type
+}
+
+// GetWeight 获取任务权重
+func (t *Task) GetWeight() int {
+	if t.Weight <= 0 {
+		return 1
+	}
+	return t.Weight
+}
+
+// IsTemplate 判断 shell 命令是否为模板，未开启模板的命令原样执行
+func (t *Task) IsTemplate() bool {
+	return t.Template || len(t.Params) != 0
+}
+
+// Validate 校验任务的执行配置，防止任务在错误的目录或以 root 用户执行
+func (t *Task) Validate() error {
+	// 校验各任务类型的执行配置
+	switch t.Type {
+	case TaskTypeExec:
+		if len(t.Args) == 0 || t.Args[0] == "" {
+			return ErrorTaskArgsEmpty
+		}
+	case TaskTypeHTTP:
+		if err := t.HTTP.Validate(); err != nil {
+			return err
+		}
+	case TaskTypeSQL:
+		if err := t.SQL.Validate(); err != nil {
+			return err
+		}
+	case TaskTypeGRPC:
+		if err := t.GRPC.Validate(); err != nil {
+			return err
+		}
+	}
+
+	// 校验任务参数声明和 shell 模板
+	if err := t.validateParams(); err != nil {
+		return err
+	}
+
+	// 工作目录必须是绝对路径
+	if t.Dir != "" && !path.IsAbs(t.Dir) && !filepath.IsAbs(t.Dir) {
+		return ErrorTaskDirNotAbs
+	}
+
+	// 环境变量名称只能包含字母、数字和下划线，且不能以数字开头
+	for key := range t.Env {
+		if !envNamePattern.MatchString(key) {
+			return ErrorTaskEnvInvalid
+		}
+	}
+
+	// 不允许以 root 用户执行
+	if t.UID != nil && *t.UID <= 0 {
+		return ErrorTaskUIDInvalid
+	}
+	if t.GID != nil && (*t.GID <= 0 || t.UID == nil) {
+		return ErrorTaskGIDInvalid
+	}
+	return nil
+}
+
+// CommandLine 获取任务执行的命令行
+func (t *Task) CommandLine() string {
+	switch t.Type {
+	case TaskTypeExec:
+		return strings.Join(t.Args, " ")
+	case TaskTypeHTTP:
+		if t.HTTP == nil {
+			return ""
+		}
+		return t.HTTP.GetMethod() + " " + t.HTTP.URL
+	case TaskTypeSQL:
+		if t.SQL == nil {
+			return ""
+		}
+		return t.SQL.Query
+	case TaskTypeGRPC:
+		if t.GRPC == nil {
+			return ""
+		}
+		return "grpc.health.v1.Health/Check " + t.GRPC.Target + " " + t.GRPC.Service
+	}
+	return t.Shell
+}
+
 // Unmarshal 反序列化任务数据
 func (t *Task) Unmarshal(data []byte) error {
 	err := json.Unmarshal(data, t)
@@ -0,0 +1,25 @@
+package common
+
+import (
+	"encoding/json"
+)
+
+// Trigger 手动触发任务信息
+type Trigger struct {
+	User     string            `json:"user"`               // 触发用户
+	Time     int64             `json:"time"`               // 触发时间
+	Workflow string            `json:"workflow,omitempty"` // 触发任务的工作流名称，为空表示手动执行
+	RunID    string            `json:"runId,omitempty"`    // 触发任务的工作流执行编号
+	Params   map[string]string `json:"params,omitempty"`   // 覆盖任务参数默认值，为空表示使用默认值
+}
+
+// NewTrigger 实例化手动触发任务信息对象
+func NewTrigger() *Trigger {
+	return &Trigger{}
+}
+
+// Unmarshal 反序列化手动触发任务信息
+func (t *Trigger) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, t)
+	return err
+}
@@ -0,0 +1,65 @@
+package common
+
+import (
+	"encoding/json"
+)
+
+// WorkerInfo 服务注册信息
+type WorkerInfo struct {
+	ID            string            `json:"id"`            // 节点编号
+	Addr          string            `json:"addr"`          // 节点对外通告地址
+	Hostname      string            `json:"hostname"`      // 主机名
+	Pid           int               `json:"pid"`           // 进程号
+	Version       string            `json:"version"`       // 服务版本
+	OS            string            `json:"os"`            // 操作系统
+	Arch          string            `json:"arch"`          // CPU 架构
+	NumCPU        int               `json:"numCPU"`        // CPU 核数
+	Labels        map[string]string `json:"labels"`        // 节点标签
+	Types         []string          `json:"types"`         // 支持的任务类型
+	Running       int               `json:"running"`       // 正在执行的任务数
+	Load          int               `json:"load"`          // 正在执行的任务权重之和
+	Capacity      int               `json:"capacity"`      // 最大负载，0 表示不限制
+	Draining      bool              `json:"draining"`      // 是否正在排空
+	StartTime     int64             `json:"startTime"`     // 服务启动时间
+	LastHeartbeat int64             `json:"lastHeartbeat"` // 最近心跳时间
+}
+
+// WorkerDetail 服务节点详情
+type WorkerDetail struct {
+	Worker *WorkerInfo `json:"worker"` // 服务注册信息
+	Logs   []*Log      `json:"logs"`   // 最近的任务执行日志
+}
+
+// NewWorkerInfo 实例化服务注册信息对象
+func NewWorkerInfo() *WorkerInfo {
+	return &WorkerInfo{}
+}
+
+// Unmarshal 反序列化服务注册信息
+func (w *WorkerInfo) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, w)
+	return err
+}
+
+// Admits 判断节点是否还能执行指定权重的任务
+func (w *WorkerInfo) Admits(weight int) bool {
+	return w.Capacity <= 0 || w.Load+weight <= w.Capacity
+}
+
+// Fits 判断节点的最大负载是否能容纳指定权重的任务
+func (w *WorkerInfo) Fits(weight int) bool {
+	return w.Capacity <= 0 || weight <= w.Capacity
+}
+
+// Supports 判断节点是否支持任务类型，未发布任务类型的旧版本节点只支持 shell 和 exec 类型
+func (w *WorkerInfo) Supports(taskType string) bool {
+	if len(w.Types) == 0 {
+		return taskType == TaskTypeShell || taskType == TaskTypeExec
+	}
+	for _, t := range w.Types {
+		if t == taskType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,270 @@
+package common
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+)
+
+// Workflow 工作流，由任务节点和依赖边组成的有向无环图
+type Workflow struct {
+	Name     string          `json:"name"`     // 工作流名称
+	CronExpr string          `json:"cronExpr"` // 根节点的 cron 表达式，为空表示只能手动执行
+	Nodes    []string        `json:"nodes"`    // 任务节点，值为任务名称
+	Edges    []*WorkflowEdge `json:"edges"`    // 依赖边
+	Disabled bool            `json:"disabled"` // 是否已暂停调度
+}
+
+// WorkflowEdge 工作流依赖边，上游节点结束且满足条件时执行下游节点
+type WorkflowEdge struct {
+	From      string `json:"from"`      // 上游任务节点
+	To        string `json:"to"`        // 下游任务节点
+	Condition string `json:"condition"` // 执行条件: success(默认), failure, always
+}
+
+// NewWorkflow 实例化工作流对象
+func NewWorkflow() *Workflow {
+	return &Workflow{}
+}
+
+// Unmarshal 反序列化工作流数据
+func (w *Workflow) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, w)
+	return err
+}
+
+// Validate 校验工作流，依赖边必须引用已声明的节点且不能成环
+func (w *Workflow) Validate() error {
+	if w.Name == "" || strings.Contains(w.Name, "/") {
+		return ErrorWorkflowNameInvalid
+	}
+	if w.CronExpr != "" {
+		if _, err := cronexpr.Parse(w.CronExpr); err != nil {
+			return err
+		}
+	}
+
+	// 节点不能为空且不能重复
+	if len(w.Nodes) == 0 {
+		return ErrorWorkflowNodesEmpty
+	}
+	inDegree := make(map[string]int, len(w.Nodes))
+	for _, node := range w.Nodes {
+		if _, ok := inDegree[node]; ok || node == "" {
+			return ErrorWorkflowNodeInvalid
+		}
+		inDegree[node] = 0
+	}
+
+	// 依赖边必须引用已声明的节点
+	for _, edge := range w.Edges {
+		_, fromOK := inDegree[edge.From]
+		_, toOK := inDegree[edge.To]
+		if !fromOK || !toOK || edge.From == edge.To {
+			return ErrorWorkflowEdgeInvalid
+		}
+		switch edge.Condition {
+		case "", EdgeSuccess, EdgeFailure, EdgeAlways:
+		default:
+			return ErrorWorkflowEdgeInvalid
+		}
+		inDegree[edge.To]++
+	}
+
+	// 拓扑排序，所有节点都能被访问时无环
+	queue := make([]string, 0, len(w.Nodes))
+	for _, node := range w.Nodes {
+		if inDegree[node] == 0 {
+			queue = append(queue, node)
+		}
+	}
+	visited := 0
+	for len(queue) != 0 {
+		node := queue[0]
+		queue = queue[1:]
+		visited++
+		for _, edge := range w.Edges {
+			if edge.From != node {
+				continue
+			}
+			if inDegree[edge.To]--; inDegree[edge.To] == 0 {
+				queue = append(queue, edge.To)
+			}
+		}
+	}
+	if visited != len(w.Nodes) {
+		return ErrorWorkflowHasCycle
+	}
+	return nil
+}
+
+// Satisfied 判断上游节点的执行状态是否满足依赖边的执行条件
+func (e *WorkflowEdge) Satisfied(status string) bool {
+	switch e.Condition {
+	case EdgeFailure:
+		return status == StatusFailure
+	case EdgeAlways:
+		return true
+	default:
+		return status == StatusSuccess
+	}
+}
+
+// WorkflowRun 工作流的一次执行
+type WorkflowRun struct {
+	ID        string              `json:"id"`        // 执行编号
+	Workflow  *Workflow           `json:"workflow"`  // 工作流定义快照
+	Status    string              `json:"status"`    // 执行状态: running, success, failure
+	User      string              `json:"user"`      // 手动执行的触发用户，为空表示 cron 调度
+	PlanTime  int64               `json:"planTime"`  // 理论调度时间
+	StartTime int64               `json:"startTime"` // 开始执行时间
+	EndTime   int64               `json:"endTime"`   // 结束执行时间
+	Nodes     map[string]*NodeRun `json:"nodes"`     // 各任务节点的执行状态
+}
+
+// NodeRun 工作流任务节点的执行状态
+type NodeRun struct {
+	Status    string `json:"status"`    // 执行状态: pending, running, success, failure, skipped
+	ExecID    string `json:"execId"`    // 执行编号
+	Worker    string `json:"worker"`    // 执行节点
+	Error     string `json:"error"`     // 执行错误
+	StartTime int64  `json:"startTime"` // 开始执行时间
+	EndTime   int64  `json:"endTime"`   // 结束执行时间
+}
+
+// NewWorkflowRun 实例化工作流执行对象，所有节点等待执行
+func NewWorkflowRun(workflow *Workflow, planTime time.Time) *WorkflowRun {
+	run := &WorkflowRun{
+		ID:        strconv.FormatInt(planTime.UnixMilli(), 10),
+		Workflow:  workflow,
+		Status:    StatusRunning,
+		PlanTime:  planTime.UnixMilli(),
+		StartTime: time.Now().UnixMilli(),
+		Nodes:     make(map[string]*NodeRun, len(workflow.Nodes)),
+	}
+	for _, node := range workflow.Nodes {
+		run.Nodes[node] = &NodeRun{Status: StatusPending}
+	}
+	return run
+}
+
+// Unmarshal 反序列化工作流执行数据
+func (r *WorkflowRun) Unmarshal(data []byte) error {
+	err := json.Unmarshal(data, r)
+	return err
+}
+
+// Finish 记录任务节点的执行结果，节点不在执行中时返回 false
+func (r *WorkflowRun) Finish(name string, result *NodeRun) bool {
+	node, ok := r.Nodes[name]
+	if !ok || node.Status != StatusRunning {
+		return false
+	}
+	result.StartTime = node.StartTime
+	r.Nodes[name] = result
+	return true
+}
+
+// Dispatch 记录执行任务节点的 worker，节点不在执行中或已被认领时返回 false
+func (r *WorkflowRun) Dispatch(name string, execID string, worker string) bool {
+	node, ok := r.Nodes[name]
+	if !ok || node.Status != StatusRunning || node.Worker != "" {
+		return false
+	}
+	node.ExecID = execID
+	node.Worker = worker
+	return true
+}
+
+// Expire 将开始时间早于 deadline 仍未被任何 worker 认领的任务节点记为执行失败，返回失败的节点
+func (r *WorkflowRun) Expire(deadline int64) []string {
+	expired := make([]string, 0)
+	for _, name := range r.Workflow.Nodes {
+		node := r.Nodes[name]
+		if node.Status != StatusRunning || node.Worker != "" || node.StartTime >= deadline {
+			continue
+		}
+		node.Status = StatusFailure
+		node.Error = ErrorWorkflowNodeNotDispatched.Error()
+		node.EndTime = time.Now().UnixMilli()
+		expired = append(expired, name)
+	}
+	return expired
+}
+
+// Advance 推进工作流执行，返回可以执行的任务节点，所有节点结束时汇总执行状态
+func (r *WorkflowRun) Advance() []string {
+	now := time.Now().UnixMilli()
+	ready := make([]string, 0)
+
+	// 上游节点结束后，所有入边的条件都满足时执行节点，否则跳过节点，跳过的节点继续影响下游节点
+	for changed := true; changed; {
+		changed = false
+		for _, name := range r.Workflow.Nodes {
+			node := r.Nodes[name]
+			if node.Status != StatusPending {
+				continue
+			}
+			run, decided := r.evaluate(name)
+			if !decided {
+				continue
+			}
+			changed = true
+			node.StartTime = now
+			if run {
+				node.Status = StatusRunning
+				ready = append(ready, name)
+			} else {
+				node.Status = StatusSkipped
+				node.EndTime = now
+			}
+		}
+	}
+
+	// 所有节点结束时，有节点执行失败则工作流执行失败
+	status := StatusSuccess
+	for _, node := range r.Nodes {
+		switch node.Status {
+		case StatusPending, StatusRunning:
+			return ready
+		case StatusFailure:
+			status = StatusFailure
+		}
+	}
+	if r.Status == StatusRunning {
+		r.Status = status
+		r.EndTime = now
+	}
+	return ready
+}
+
+// evaluate 判断节点是否执行，上游节点尚未全部结束时 decided 为 false
+func (r *WorkflowRun) evaluate(name string) (run bool, decided bool) {
+	run = true
+	for _, edge := range r.Workflow.Edges {
+		if edge.To != name {
+			continue
+		}
+		parent := r.Nodes[edge.From]
+		if parent.Status == StatusPending || parent.Status == StatusRunning {
+			return false, false
+		}
+		if !edge.Satisfied(parent.Status) {
+			run = false
+		}
+	}
+	return run, true
+}
+
+// NodeStatus 将任务执行状态转换为工作流节点执行状态
+func NodeStatus(status string) string {
+	switch status {
+	case StatusSuccess, StatusSkipped:
+		return status
+	default:
+		return StatusFailure
+	}
+}
@@ -0,0 +1,218 @@
+package common
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestWorkflowValidate(t *testing.T) {
+	edge := func(from, to, condition string) *WorkflowEdge {
+		return &WorkflowEdge{From: from, To: to, Condition: condition}
+	}
+
+	tests := []struct {
+		name     string
+		workflow *Workflow
+		want     error
+	}{
+		{
+			name:     "单节点",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a"}},
+			want:     nil,
+		},
+		{
+			name: "菱形依赖",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a", "b", "c", "d"}, Edges: []*WorkflowEdge{
+				edge("a", "b", ""), edge("a", "c", EdgeFailure), edge("b", "d", EdgeAlways), edge("c", "d", EdgeSuccess),
+			}},
+			want: nil,
+		},
+		{
+			name:     "名称为空",
+			workflow: &Workflow{Nodes: []string{"a"}},
+			want:     ErrorWorkflowNameInvalid,
+		},
+		{
+			name:     "名称包含 /",
+			workflow: &Workflow{Name: "a/b", Nodes: []string{"a"}},
+			want:     ErrorWorkflowNameInvalid,
+		},
+		{
+			name:     "节点为空",
+			workflow: &Workflow{Name: "wf"},
+			want:     ErrorWorkflowNodesEmpty,
+		},
+		{
+			name:     "节点重复",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a", "a"}},
+			want:     ErrorWorkflowNodeInvalid,
+		},
+		{
+			name:     "依赖边引用未声明的节点",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a"}, Edges: []*WorkflowEdge{edge("a", "b", "")}},
+			want:     ErrorWorkflowEdgeInvalid,
+		},
+		{
+			name:     "依赖边指向自身",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a"}, Edges: []*WorkflowEdge{edge("a", "a", "")}},
+			want:     ErrorWorkflowEdgeInvalid,
+		},
+		{
+			name:     "执行条件不合法",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a", "b"}, Edges: []*WorkflowEdge{edge("a", "b", "never")}},
+			want:     ErrorWorkflowEdgeInvalid,
+		},
+		{
+			name: "两个节点成环",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a", "b"}, Edges: []*WorkflowEdge{
+				edge("a", "b", ""), edge("b", "a", ""),
+			}},
+			want: ErrorWorkflowHasCycle,
+		},
+		{
+			name: "根节点之后成环",
+			workflow: &Workflow{Name: "wf", Nodes: []string{"a", "b", "c", "d"}, Edges: []*WorkflowEdge{
+				edge("a", "b", ""), edge("b", "c", ""), edge("c", "d", ""), edge("d", "b", ""),
+			}},
+			want: ErrorWorkflowHasCycle,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.workflow.Validate(); got != tt.want {
+				t.Fatalf("Validate() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWorkflowRunAdvance(t *testing.T) {
+	// a -> b (success), a -> c (failure), b -> d (always), c -> d (always)
+	workflow := &Workflow{Name: "wf", Nodes: []string{"a", "b", "c", "d"}, Edges: []*WorkflowEdge{
+		{From: "a", To: "b"},
+		{From: "a", To: "c", Condition: EdgeFailure},
+		{From: "b", To: "d", Condition: EdgeAlways},
+		{From: "c", To: "d", Condition: EdgeAlways},
+	}}
+
+	tests := []struct {
+		name     string
+		results  map[string]string // 依次结束的节点及其执行状态
+		order    []string
+		ready    [][]string // 每次推进后可以执行的节点
+		statuses map[string]string
+		status   string
+	}{
+		{
+			name:  "上游成功时跳过 failure 分支",
+			order: []string{"a", "b", "d"},
+			results: map[string]string{
+				"a": StatusSuccess, "b": StatusSuccess, "d": StatusSuccess,
+			},
+			ready: [][]string{{"a"}, {"b"}, {"d"}, {}},
+			statuses: map[string]string{
+				"a": StatusSuccess, "b": StatusSuccess, "c": StatusSkipped, "d": StatusSuccess,
+			},
+			status: StatusSuccess,
+		},
+		{
+			name:  "上游失败时执行 failure 分支，工作流执行失败",
+			order: []string{"a", "c", "d"},
+			results: map[string]string{
+				"a": StatusFailure, "c": StatusSuccess, "d": StatusSuccess,
+			},
+			ready: [][]string{{"a"}, {"c"}, {"d"}, {}},
+			statuses: map[string]string{
+				"a": StatusFailure, "b": StatusSkipped, "c": StatusSuccess, "d": StatusSuccess,
+			},
+			status: StatusFailure,
+		},
+		{
+			name:  "跳过的节点影响 success 下游",
+			order: []string{"a", "b", "d"},
+			results: map[string]string{
+				"a": StatusSuccess, "b": StatusSkipped, "d": StatusFailure,
+			},
+			ready: [][]string{{"a"}, {"b"}, {"d"}, {}},
+			statuses: map[string]string{
+				"a": StatusSuccess, "b": StatusSkipped, "c": StatusSkipped, "d": StatusFailure,
+			},
+			status: StatusFailure,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			run := NewWorkflowRun(workflow, time.Now())
+			assertReady(t, run.Advance(), tt.ready[0])
+			for i, name := range tt.order {
+				if run.Status != StatusRunning {
+					t.Fatalf("Status = %s before %s finished, want running", run.Status, name)
+				}
+				if !run.Finish(name, &NodeRun{Status: tt.results[name]}) {
+					t.Fatalf("Finish(%s) = false, want true", name)
+				}
+				assertReady(t, run.Advance(), tt.ready[i+1])
+			}
+			for name, want := range tt.statuses {
+				if got := run.Nodes[name].Status; got != want {
+					t.Fatalf("node %s status = %s, want %s", name, got, want)
+				}
+			}
+			if run.Status != tt.status {
+				t.Fatalf("Status = %s, want %s", run.Status, tt.status)
+			}
+		})
+	}
+}
+
+func TestWorkflowRunFinishAndExpire(t *testing.T) {
+	workflow := &Workflow{Name: "wf", Nodes: []string{"a", "b"}, Edges: []*WorkflowEdge{{From: "a", To: "b", Condition: EdgeAlways}}}
+	run := NewWorkflowRun(workflow, time.Now())
+	run.Advance()
+
+	// 尚未执行的节点不能记录结果
+	if run.Finish("b", &NodeRun{Status: StatusSuccess}) {
+		t.Fatal("Finish(b) = true for pending node, want false")
+	}
+
+	// 已认领的节点不会超时
+	if !run.Dispatch("a", "exec", "worker") || run.Dispatch("a", "exec2", "worker2") {
+		t.Fatal("Dispatch(a) should succeed exactly once")
+	}
+	if expired := run.Expire(time.Now().Add(time.Hour).UnixMilli()); len(expired) != 0 {
+		t.Fatalf("Expire() = %v for dispatched node, want none", expired)
+	}
+
+	// 未被认领的节点超时后记为失败，always 下游继续执行
+	if !run.Finish("a", &NodeRun{Status: StatusSuccess}) {
+		t.Fatal("Finish(a) = false, want true")
+	}
+	assertReady(t, run.Advance(), []string{"b"})
+	if expired := run.Expire(run.Nodes["b"].StartTime); len(expired) != 0 {
+		t.Fatalf("Expire() = %v before deadline, want none", expired)
+	}
+	if expired := run.Expire(run.Nodes["b"].StartTime + 1); !reflect.DeepEqual(expired, []string{"b"}) {
+		t.Fatalf("Expire() = %v, want [b]", expired)
+	}
+	run.Advance()
+	if run.Status != StatusFailure {
+		t.Fatalf("Status = %s, want failure", run.Status)
+	}
+
+	// 已结束的节点不再记录结果
+	if run.Finish("b", &NodeRun{Status: StatusSuccess}) {
+		t.Fatal("Finish(b) = true for expired node, want false")
+	}
+}
+
+func assertReady(t *testing.T, got []string, want []string) {
+	t.Helper()
+	sort.Strings(got)
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("Advance() = %v, want %v", got, want)
+	}
+}
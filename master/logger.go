@@ -2,8 +2,10 @@ package master
 
 import (
 	"context"
+	"strconv"
 	"time"
 
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
 
@@ -43,10 +45,7 @@ func (l *Logger) Init() error {
 }
 
 // ListLog 获取任务执行日志列表
-func (l *Logger) ListLog(name string, skip int, limit int) ([]*common.Log, error) {
-	// 实例化任务执行日志过滤条件对象
-	filter := common.NewLogFilter(name)
-
+func (l *Logger) ListLog(filter *common.LogFilter, skip int, limit int) ([]*common.Log, error) {
 	// 实例化任务执行日志排序规则对象，按照开始时间倒序排序
 	sorter := common.NewLogSorter(-1)
 
@@ -75,3 +74,69 @@ func (l *Logger) ListLog(name string, skip int, limit int) ([]*common.Log, error
 
 	return logList, nil
 }
+
+// ListLogGroup 按照调度分页获取任务执行日志分组，过滤条件用于选择调度，每个分组包含该次调度的全部日志
+func (l *Logger) ListLogGroup(filter *common.LogFilter, skip int, limit int) ([]*common.LogGroup, error) {
+	// 按照任务名称和调度时间分组，以最近的开始时间倒序分页
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: filter}},
+		{{Key: "$group", Value: bson.D{
+			{Key: "_id", Value: bson.D{{Key: "taskName", Value: "$taskName"}, {Key: "planTime", Value: "$planTime"}}},
+			{Key: "startTime", Value: bson.D{{Key: "$max", Value: "$startTime"}}},
+		}}},
+		{{Key: "$sort", Value: bson.D{{Key: "startTime", Value: -1}}}},
+		{{Key: "$skip", Value: skip}},
+		{{Key: "$limit", Value: limit}},
+	}
+	cursor, err := l.Collection.Aggregate(context.TODO(), pipeline)
+	if err != nil {
+		return nil, err
+	}
+	var keys []struct {
+		ID struct {
+			TaskName string `bson:"taskName"`
+			PlanTime int64  `bson:"planTime"`
+		} `bson:"_id"`
+	}
+	if err := cursor.All(context.TODO(), &keys); err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return make([]*common.LogGroup, 0), nil
+	}
+
+	// 查询分页内各次调度的全部日志
+	conditions := make(bson.A, 0, len(keys))
+	for _, key := range keys {
+		conditions = append(conditions, bson.D{{Key: "taskName", Value: key.ID.TaskName}, {Key: "planTime", Value: key.ID.PlanTime}})
+	}
+	opts := options.Find().SetSort(common.NewLogSorter(-1))
+	logCursor, err := l.Collection.Find(context.TODO(), bson.D{{Key: "$or", Value: conditions}}, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer func(cur *mongo.Cursor) {
+		_ = cur.Close(context.TODO())
+	}(logCursor)
+	logList := make([]*common.Log, 0)
+	for logCursor.Next(context.TODO()) {
+		log := common.NewLog()
+		if err := logCursor.Decode(log); err != nil {
+			continue // bson 数据格式不正确，跳过该条数据
+		}
+		logList = append(logList, log)
+	}
+
+	// 按照分页顺序返回日志分组
+	groupTable := make(map[string]*common.LogGroup)
+	for _, group := range common.GroupLogs(logList) {
+		groupTable[group.TaskName+"/"+strconv.FormatInt(group.PlanTime, 10)] = group
+	}
+	groupList := make([]*common.LogGroup, 0, len(keys))
+	for _, key := range keys {
+		if group, ok := groupTable[key.ID.TaskName+"/"+strconv.FormatInt(key.ID.PlanTime, 10)]; ok {
+			groupList = append(groupList, group)
+		}
+	}
+	return groupList, nil
+}
@@ -3,8 +3,11 @@ package master
 import (
 	"context"
 	"encoding/json"
+	"fmt"
+	"strconv"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientV3 "go.etcd.io/etcd/client/v3"
 
 	"crontab/common"
@@ -15,9 +18,10 @@ var GlobalManager = NewManager()
 
 // Manager 任务管理器
 type Manager struct {
-	Client *clientV3.Client
-	KV     clientV3.KV
-	Lease  clientV3.Lease
+	Client  *clientV3.Client
+	KV      clientV3.KV
+	Lease   clientV3.Lease
+	Watcher clientV3.Watcher
 }
 
 // NewManager 实例化任务管理对象
@@ -43,6 +47,7 @@ func (m *Manager) Init() error {
 	m.Client = client
 	m.KV = clientV3.NewKV(client)
 	m.Lease = clientV3.NewLease(client)
+	m.Watcher = clientV3.NewWatcher(client)
 
 	return nil
 }
@@ -78,6 +83,9 @@ func (m *Manager) DeleteTask(name string) (*common.Task, error) {
 		return nil, err
 	}
 
+	// 删除任务最近调度时间，避免同名新任务补偿旧任务错过的调度
+	_, _ = m.KV.Delete(context.TODO(), common.PathFire+name)
+
 	// 反序列化旧任务
 	var oldTask *common.Task
 	if len(resp.PrevKvs) != 0 {
@@ -106,8 +114,91 @@ func (m *Manager) ListTask() ([]*common.Task, error) {
 	return listTask, nil
 }
 
-// KillTask 通知 worker 服务杀死任务
-func (m *Manager) KillTask(name string) error {
+// PauseTask 暂停任务调度
+func (m *Manager) PauseTask(name string) (*common.Task, error) {
+	return m.setTaskDisabled(name, true)
+}
+
+// ResumeTask 恢复任务调度
+func (m *Manager) ResumeTask(name string) (*common.Task, error) {
+	return m.setTaskDisabled(name, false)
+}
+
+// setTaskDisabled 修改任务的暂停状态
+func (m *Manager) setTaskDisabled(name string, disabled bool) (*common.Task, error) {
+	// 获取任务
+	resp, err := m.KV.Get(context.TODO(), common.PathTask+name)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, common.ErrorTaskNotFound
+	}
+
+	// 反序列化任务
+	task := common.NewTask()
+	if err := task.Unmarshal(resp.Kvs[0].Value); err != nil {
+		return nil, err
+	}
+
+	// 恢复调度前将任务最近调度时间推进至当前时间，暂停期间的调度不再补偿
+	if !disabled {
+		fireTime := strconv.FormatInt(time.Now().UnixMilli(), 10)
+		if _, err := m.KV.Put(context.TODO(), common.PathFire+name, fireTime); err != nil {
+			return nil, err
+		}
+	}
+
+	// 保存修改后的任务
+	task.Disabled = disabled
+	if _, err := m.SaveTask(task); err != nil {
+		return nil, err
+	}
+	return task, nil
+}
+
+// RunTask 通知 worker 服务立即执行一次任务
+func (m *Manager) RunTask(name string, trigger *common.Trigger) error {
+	// 判断任务是否存在
+	resp, err := m.KV.Get(context.TODO(), common.PathTask+name)
+	if err != nil {
+		return err
+	}
+	if len(resp.Kvs) == 0 {
+		return common.ErrorTaskNotFound
+	}
+
+	// 校验覆盖的任务参数
+	task := common.NewTask()
+	if err := task.Unmarshal(resp.Kvs[0].Value); err != nil {
+		return err
+	}
+	if _, err := task.ResolveParams(trigger.Params); err != nil {
+		return err
+	}
+
+	// 序列化手动触发任务信息
+	value, err := json.Marshal(trigger)
+	if err != nil {
+		return err
+	}
+
+	// 创建租约
+	leaseResp, err := m.Lease.Grant(context.TODO(), 1)
+	if err != nil {
+		return err
+	}
+
+	// 设置手动执行任务标记
+	if _, err := m.KV.Put(context.TODO(), common.PathRun+name, string(value), clientV3.WithLease(leaseResp.ID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// KillTask 通知 worker 服务杀死任务，指定执行编号时只杀死该次执行
+func (m *Manager) KillTask(name string, execID string) error {
 	// 创建租约
 	resp, err := m.Lease.Grant(context.TODO(), 1)
 	if err != nil {
@@ -115,17 +206,180 @@ func (m *Manager) KillTask(name string) error {
 	}
 
 	// 设置杀死任务标记
-	if _, err := m.KV.Put(context.TODO(), common.PathKill+name, "", clientV3.WithLease(resp.ID)); err != nil {
+	if _, err := m.KV.Put(context.TODO(), common.PathKill+name, execID, clientV3.WithLease(resp.ID)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// ListRunning 获取集群中正在执行的任务列表
+func (m *Manager) ListRunning() ([]*common.Execution, error) {
+	// 获取正在执行的任务
+	resp, err := m.KV.Get(context.TODO(), common.PathRunning, clientV3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	// 遍历正在执行的任务，依次反序列化
+	runningList := make([]*common.Execution, 0)
+	for _, kv := range resp.Kvs {
+		execution := common.NewExecution()
+		if err := execution.Unmarshal(kv.Value); err == nil {
+			runningList = append(runningList, execution)
+		}
+	}
+	return runningList, nil
+}
+
+// TailTask 监听任务的实时输出，上下文取消后关闭输出片段通道
+func (m *Manager) TailTask(ctx context.Context, name string) (<-chan *common.Chunk, error) {
+	// 登记实时输出订阅者，worker 只在有订阅者时推送输出，上下文取消后撤销租约取消订阅
+	leaseResp, err := m.Lease.Grant(ctx, common.TailTTL)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := m.KV.Put(ctx, common.PathTail+name+"/"+common.NewID(), "", clientV3.WithLease(leaseResp.ID)); err != nil {
+		return nil, err
+	}
+	keepAliveChan, err := m.Lease.KeepAlive(ctx, leaseResp.ID)
+	if err != nil {
+		return nil, err
+	}
+	go func() {
+		for range keepAliveChan {
+		}
+		_, _ = m.Lease.Revoke(context.TODO(), leaseResp.ID)
+	}()
+
+	// 获取尚未过期的输出片段
+	prefix := common.PathOutput + name + "/"
+	resp, err := m.KV.Get(ctx, prefix, clientV3.WithPrefix(), clientV3.WithSort(clientV3.SortByCreateRevision, clientV3.SortAscend))
+	if err != nil {
+		return nil, err
+	}
+
+	chunkChan := make(chan *common.Chunk)
+	go func() {
+		defer close(chunkChan)
+
+		// 推送输出片段
+		push := func(value []byte) bool {
+			chunk := common.NewChunk()
+			if err := chunk.Unmarshal(value); err != nil {
+				return true
+			}
+			select {
+			case chunkChan <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		// 先推送已有的输出片段
+		for _, kv := range resp.Kvs {
+			if !push(kv.Value) {
+				return
+			}
+		}
+
+		// 监听新的输出片段
+		watchChan := m.Watcher.Watch(ctx, prefix, clientV3.WithPrefix(), clientV3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchChan {
+			for _, e := range watchResp.Events {
+				if e.Type == mvccpb.PUT && !push(e.Kv.Value) {
+					return
+				}
+			}
+		}
+	}()
+
+	return chunkChan, nil
+}
+
+// CheckTaskType 判断是否有在线节点支持任务类型，内置的 shell 和 exec 类型以及没有在线节点时不校验
+func (m *Manager) CheckTaskType(taskType string) error {
+	if taskType == common.TaskTypeShell || taskType == common.TaskTypeExec {
+		return nil
+	}
+	workerList, err := m.ListWorker()
+	if err != nil {
 		return err
 	}
+	if len(workerList) == 0 {
+		return nil
+	}
+	for _, info := range workerList {
+		if info.Supports(taskType) {
+			return nil
+		}
+	}
+	return common.ErrorTaskTypeUnsupported
+}
 
+// CheckTaskWeight 判断任务权重是否超过所有可执行节点的最大负载，没有在线节点满足节点选择器时不校验
+func (m *Manager) CheckTaskWeight(task *common.Task) error {
+	workerList, err := m.ListWorker()
+	if err != nil {
+		return err
+	}
+	matched := false
+	for _, info := range workerList {
+		if !info.Supports(task.GetType()) || !task.Selector.Matches(info.Labels) {
+			continue
+		}
+		if info.Fits(task.GetWeight()) {
+			return nil
+		}
+		matched = true
+	}
+	if matched {
+		return common.ErrorTaskTooHeavy
+	}
 	return nil
 }
 
+// GetWorker 获取服务注册信息
+func (m *Manager) GetWorker(id string) (*common.WorkerInfo, error) {
+	// 获取服务注册信息
+	resp, err := m.KV.Get(context.TODO(), common.PathWorker+id)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, common.ErrorWorkerNotFound
+	}
+
+	// 反序列化服务注册信息
+	info := common.NewWorkerInfo()
+	_ = info.Unmarshal(resp.Kvs[0].Value)
+	info.ID = id
+	return info, nil
+}
+
+// DrainWorker 通知 worker 服务进入排空，不再执行新的任务
+func (m *Manager) DrainWorker(id string) error {
+	// 判断节点是否在线
+	if _, err := m.GetWorker(id); err != nil {
+		return err
+	}
+
+	// 设置节点排空标记
+	_, err := m.KV.Put(context.TODO(), common.PathDrain+id, strconv.FormatInt(time.Now().UnixMilli(), 10))
+	return err
+}
+
+// UndrainWorker 通知 worker 服务取消排空
+func (m *Manager) UndrainWorker(id string) error {
+	_, err := m.KV.Delete(context.TODO(), common.PathDrain+id)
+	return err
+}
+
 // ListWorker 获取服务注册列表
-func (m *Manager) ListWorker() ([]string, error) {
+func (m *Manager) ListWorker() ([]*common.WorkerInfo, error) {
 	// 初始化服务注册列表
-	workerList := make([]string, 0)
+	workerList := make([]*common.WorkerInfo, 0)
 
 	// 获取服务注册目录下所有 kv
 	resp, err := m.KV.Get(context.TODO(), common.PathWorker, clientV3.WithPrefix())
@@ -133,10 +387,140 @@ func (m *Manager) ListWorker() ([]string, error) {
 		return workerList, err
 	}
 
-	// 解析所有节点的 IP
+	// 解析所有节点的注册信息
 	for _, kv := range resp.Kvs {
-		workerList = append(workerList, common.ExtractName(string(kv.Key), common.PathWorker))
+		info := common.NewWorkerInfo()
+		_ = info.Unmarshal(kv.Value)
+		info.ID = common.ExtractName(string(kv.Key), common.PathWorker)
+		workerList = append(workerList, info)
 	}
 
 	return workerList, nil
 }
+
+// SaveWorkflow 保存工作流至 etcd 中，工作流引用的任务必须存在
+func (m *Manager) SaveWorkflow(workflow *common.Workflow) error {
+	// 判断任务节点是否存在
+	for _, name := range workflow.Nodes {
+		resp, err := m.KV.Get(context.TODO(), common.PathTask+name, clientV3.WithCountOnly())
+		if err != nil {
+			return err
+		}
+		if resp.Count == 0 {
+			return fmt.Errorf("%w: %s", common.ErrorTaskNotFound, name)
+		}
+	}
+
+	// 序列化工作流对象
+	value, err := json.Marshal(workflow)
+	if err != nil {
+		return err
+	}
+
+	// 保存工作流
+	_, err = m.KV.Put(context.TODO(), common.PathWorkflow+workflow.Name, string(value))
+	return err
+}
+
+// DeleteWorkflow 从 etcd 中删除工作流，工作流执行状态随租约过期删除
+func (m *Manager) DeleteWorkflow(name string) error {
+	_, err := m.KV.Delete(context.TODO(), common.PathWorkflow+name)
+	return err
+}
+
+// ListWorkflow 从 etcd 中获取工作流列表
+func (m *Manager) ListWorkflow() ([]*common.Workflow, error) {
+	// 获取工作流列表
+	resp, err := m.KV.Get(context.TODO(), common.PathWorkflow, clientV3.WithPrefix())
+	if err != nil {
+		return nil, err
+	}
+
+	// 遍历工作流列表，依次反序列化
+	workflowList := make([]*common.Workflow, 0)
+	for _, kv := range resp.Kvs {
+		workflow := common.NewWorkflow()
+		if err := workflow.Unmarshal(kv.Value); err == nil {
+			workflowList = append(workflowList, workflow)
+		}
+	}
+	return workflowList, nil
+}
+
+// RunWorkflow 立即执行一次工作流，创建工作流执行并通知 worker 服务执行根节点
+func (m *Manager) RunWorkflow(name string, user string) (*common.WorkflowRun, error) {
+	// 获取工作流
+	resp, err := m.KV.Get(context.TODO(), common.PathWorkflow+name)
+	if err != nil {
+		return nil, err
+	}
+	if len(resp.Kvs) == 0 {
+		return nil, common.ErrorWorkflowNotFound
+	}
+	workflow := common.NewWorkflow()
+	if err := workflow.Unmarshal(resp.Kvs[0].Value); err != nil {
+		return nil, err
+	}
+
+	// 构建工作流执行
+	run := common.NewWorkflowRun(workflow, time.Now())
+	run.User = user
+	ready := run.Advance()
+	value, err := json.Marshal(run)
+	if err != nil {
+		return nil, err
+	}
+
+	// 工作流执行状态随租约过期删除
+	leaseResp, err := m.Lease.Grant(context.TODO(), common.WorkflowRunTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	// 事务创建工作流执行
+	key := common.PathWorkflowRun + name + "/" + run.ID
+	txnResp, err := m.KV.Txn(context.TODO()).
+		If(clientV3.Compare(clientV3.CreateRevision(key), "=", 0)).
+		Then(clientV3.OpPut(key, string(value), clientV3.WithLease(leaseResp.ID))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txnResp.Succeeded {
+		_, _ = m.Lease.Revoke(context.TODO(), leaseResp.ID)
+		return nil, common.ErrorWorkflowRunExists
+	}
+
+	// 通知 worker 服务执行根节点
+	trigger := common.NewTrigger()
+	trigger.User = user
+	trigger.Time = time.Now().UnixMilli()
+	trigger.Workflow = name
+	trigger.RunID = run.ID
+	for _, node := range ready {
+		if err := m.RunTask(node, trigger); err != nil {
+			return nil, err
+		}
+	}
+	return run, nil
+}
+
+// ListWorkflowRun 获取工作流最近的执行列表，按照执行编号倒序排序
+func (m *Manager) ListWorkflowRun(name string, limit int) ([]*common.WorkflowRun, error) {
+	// 获取工作流执行列表
+	resp, err := m.KV.Get(context.TODO(), common.PathWorkflowRun+name+"/", clientV3.WithPrefix(),
+		clientV3.WithSort(clientV3.SortByKey, clientV3.SortDescend), clientV3.WithLimit(int64(limit)))
+	if err != nil {
+		return nil, err
+	}
+
+	// 遍历工作流执行列表，依次反序列化
+	runList := make([]*common.WorkflowRun, 0)
+	for _, kv := range resp.Kvs {
+		run := &common.WorkflowRun{}
+		if err := run.Unmarshal(kv.Value); err == nil {
+			runList = append(runList, run)
+		}
+	}
+	return runList, nil
+}
@@ -1,6 +1,8 @@
 package master
 
 import (
+	"encoding/json"
+	"fmt"
 	"net/http"
 	"strconv"
 	"time"
@@ -28,9 +30,22 @@ func (m *Server) Init() error {
 	mux.HandleFunc("/task/save", handleSaveTask)
 	mux.HandleFunc("/task/delete", handleDeleteTask)
 	mux.HandleFunc("/task/list", handleListTask)
+	mux.HandleFunc("/task/pause", handlePauseTask)
+	mux.HandleFunc("/task/resume", handleResumeTask)
+	mux.HandleFunc("/task/run", handleRunTask)
 	mux.HandleFunc("/task/kill", handleKillTask)
 	mux.HandleFunc("/task/log", handleTaskLog)
+	mux.HandleFunc("/task/tail", handleTailTask)
+	mux.HandleFunc("/task/running", handleRunningTask)
+	mux.HandleFunc("/workflow/save", handleSaveWorkflow)
+	mux.HandleFunc("/workflow/delete", handleDeleteWorkflow)
+	mux.HandleFunc("/workflow/list", handleListWorkflow)
+	mux.HandleFunc("/workflow/run", handleRunWorkflow)
+	mux.HandleFunc("/workflow/runs", handleWorkflowRuns)
 	mux.HandleFunc("/worker/list", handleWorkerList)
+	mux.HandleFunc("/worker/detail", handleWorkerDetail)
+	mux.HandleFunc("/worker/drain", handleDrainWorker)
+	mux.HandleFunc("/worker/undrain", handleUndrainWorker)
 
 	// 配置静态文件服务
 	fileHandler := http.FileServer(http.Dir(GlobalConfig.WebPath))
@@ -57,6 +72,7 @@ func handleSaveTask(w http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		data, _ := response.Build(common.StateFailure, err.Error(), nil)
 		_, _ = w.Write(data)
+		return
 	}
 
 	// 序列化任务数据
@@ -64,6 +80,28 @@ func handleSaveTask(w http.ResponseWriter, r *http.Request) {
 	if err := task.Unmarshal([]byte(r.PostForm.Get("task"))); err != nil {
 		data, _ := response.Build(common.StateFailure, err.Error(), nil)
 		_, _ = w.Write(data)
+		return
+	}
+
+	// 校验任务的执行配置
+	if err := task.Validate(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 拒绝没有在线节点支持的任务类型
+	if err := GlobalManager.CheckTaskType(task.GetType()); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 拒绝权重超过所有可执行节点最大负载的任务
+	if err := GlobalManager.CheckTaskWeight(task); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
 	}
 
 	// 保存任务至 etcd 中
@@ -71,6 +109,7 @@ func handleSaveTask(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		data, _ := response.Build(common.StateFailure, err.Error(), nil)
 		_, _ = w.Write(data)
+		return
 	}
 
 	// 返回旧任务响应
@@ -120,8 +159,102 @@ func handleListTask(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(data)
 }
 
-// handleKillTask 杀死任务接口
+// handlePauseTask 暂停任务接口
 // POST {"name": "task1"}
+func handlePauseTask(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 暂停任务调度
+	task, err := GlobalManager.PauseTask(r.PostForm.Get("name"))
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回任务响应
+	data, _ := response.Build(common.StateSuccess, "", task)
+	_, _ = w.Write(data)
+}
+
+// handleResumeTask 恢复任务接口
+// POST {"name": "task1"}
+func handleResumeTask(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 恢复任务调度
+	task, err := GlobalManager.ResumeTask(r.PostForm.Get("name"))
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回任务响应
+	data, _ := response.Build(common.StateSuccess, "", task)
+	_, _ = w.Write(data)
+}
+
+// handleRunTask 手动执行任务接口
+// POST {"name": "task1", "user": "admin", "params": "{\"date\": \"20240101\"}"}
+func handleRunTask(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 实例化手动触发任务信息对象，未指定触发用户时记录请求来源地址
+	trigger := common.NewTrigger()
+	trigger.User = r.PostForm.Get("user")
+	if trigger.User == "" {
+		trigger.User = r.RemoteAddr
+	}
+	trigger.Time = time.Now().UnixMilli()
+
+	// 反序列化覆盖的任务参数
+	if params := r.PostForm.Get("params"); params != "" {
+		if err := json.Unmarshal([]byte(params), &trigger.Params); err != nil {
+			data, _ := response.Build(common.StateFailure, err.Error(), nil)
+			_, _ = w.Write(data)
+			return
+		}
+	}
+
+	// 通知 worker 服务立即执行任务
+	if err := GlobalManager.RunTask(r.PostForm.Get("name"), trigger); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回成功响应
+	data, _ := response.Build(common.StateSuccess, "", nil)
+	_, _ = w.Write(data)
+}
+
+// handleKillTask 杀死任务接口
+// POST {"name": "task1", "execId": ""}
 func handleKillTask(w http.ResponseWriter, r *http.Request) {
 	// 实例化通讯响应对象
 	response := common.NewResponse()
@@ -133,7 +266,7 @@ func handleKillTask(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// 通知 worker 服务杀死任务
-	if err := GlobalManager.KillTask(r.PostForm.Get("name")); err != nil {
+	if err := GlobalManager.KillTask(r.PostForm.Get("name"), r.PostForm.Get("execId")); err != nil {
 		data, _ := response.Build(common.StateFailure, err.Error(), nil)
 		_, _ = w.Write(data)
 	}
@@ -143,8 +276,8 @@ func handleKillTask(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(data)
 }
 
-// handleTaskLog 获取任务日志接口
-// GET /task/log?name=task1&skip=0&limit=10
+// handleTaskLog 获取任务日志接口，group=1 时 skip 和 limit 按照调度分组计数
+// GET /task/log?name=task1&skip=0&limit=10&status=failure&exitCode=1&group=1
 func handleTaskLog(w http.ResponseWriter, r *http.Request) {
 	// 实例化通讯响应对象
 	response := common.NewResponse()
@@ -166,11 +299,32 @@ func handleTaskLog(w http.ResponseWriter, r *http.Request) {
 		limit = 10
 	}
 
+	// 实例化任务执行日志过滤条件对象，可按执行状态和退出码过滤
+	filter := common.NewLogFilter(name)
+	filter.Status = r.Form.Get("status")
+	if exitCode, err := strconv.Atoi(r.Form.Get("exitCode")); err == nil {
+		filter.ExitCode = &exitCode
+	}
+
+	// 按照调度分页返回日志分组，汇总广播执行的各节点结果
+	if r.Form.Get("group") == "1" {
+		groupList, err := GlobalLogger.ListLogGroup(filter, skip, limit)
+		if err != nil {
+			data, _ := response.Build(common.StateFailure, err.Error(), nil)
+			_, _ = w.Write(data)
+			return
+		}
+		data, _ := response.Build(common.StateSuccess, "", groupList)
+		_, _ = w.Write(data)
+		return
+	}
+
 	// 从 mongodb 中获取任务执行日志列表
-	logList, err := GlobalLogger.ListLog(name, skip, limit)
+	logList, err := GlobalLogger.ListLog(filter, skip, limit)
 	if err != nil {
 		data, _ := response.Build(common.StateFailure, err.Error(), nil)
 		_, _ = w.Write(data)
+		return
 	}
 
 	// 返回任务执行日志列表响应
@@ -178,6 +332,70 @@ func handleTaskLog(w http.ResponseWriter, r *http.Request) {
 	_, _ = w.Write(data)
 }
 
+// handleRunningTask 获取正在执行的任务接口
+// GET /task/running
+func handleRunningTask(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 从 etcd 中获取正在执行的任务列表
+	runningList, err := GlobalManager.ListRunning()
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回正在执行的任务列表响应
+	data, _ := response.Build(common.StateSuccess, "", runningList)
+	_, _ = w.Write(data)
+}
+
+// handleTailTask 实时查看任务输出接口，以 Server-Sent Events 推送输出片段
+// GET /task/tail?name=task1
+func handleTailTask(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 GET 参数
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 监听任务的实时输出
+	chunkChan, err := GlobalManager.TailTask(r.Context(), r.Form.Get("name"))
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 长连接不受服务写入超时限制
+	controller := http.NewResponseController(w)
+	_ = controller.SetWriteDeadline(time.Time{})
+
+	// 写入 Server-Sent Events 响应头
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	_ = controller.Flush()
+
+	// 推送输出片段，客户端断开后结束
+	for chunk := range chunkChan {
+		data, err := json.Marshal(chunk)
+		if err != nil {
+			continue
+		}
+		if _, err := fmt.Fprintf(w, "data: %s\n\n", data); err != nil {
+			return
+		}
+		_ = controller.Flush()
+	}
+}
+
 // handleWorkerList 获取服务注册接口
 // GET /worker/list
 func handleWorkerList(w http.ResponseWriter, r *http.Request) {
@@ -195,3 +413,238 @@ func handleWorkerList(w http.ResponseWriter, r *http.Request) {
 	data, _ := response.Build(common.StateSuccess, "", workerList)
 	_, _ = w.Write(data)
 }
+
+// handleWorkerDetail 获取服务节点详情接口
+// GET /worker/detail?id=worker-1&limit=10
+func handleWorkerDetail(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 GET 参数
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil {
+		limit = 10
+	}
+
+	// 从 etcd 中获取服务注册信息
+	detail := &common.WorkerDetail{}
+	detail.Worker, err = GlobalManager.GetWorker(r.Form.Get("id"))
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 从 mongodb 中获取节点最近的任务执行日志
+	filter := common.NewLogFilter("")
+	filter.Worker = detail.Worker.ID
+	detail.Logs, err = GlobalLogger.ListLog(filter, 0, limit)
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回服务节点详情响应
+	data, _ := response.Build(common.StateSuccess, "", detail)
+	_, _ = w.Write(data)
+}
+
+// handleDrainWorker 排空节点接口
+// POST {"id": "worker-1"}
+func handleDrainWorker(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 通知 worker 服务进入排空
+	if err := GlobalManager.DrainWorker(r.PostForm.Get("id")); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回成功响应
+	data, _ := response.Build(common.StateSuccess, "", nil)
+	_, _ = w.Write(data)
+}
+
+// handleUndrainWorker 取消排空节点接口
+// POST {"id": "worker-1"}
+func handleUndrainWorker(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 通知 worker 服务取消排空
+	if err := GlobalManager.UndrainWorker(r.PostForm.Get("id")); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回成功响应
+	data, _ := response.Build(common.StateSuccess, "", nil)
+	_, _ = w.Write(data)
+}
+
+// handleSaveWorkflow 保存工作流接口
+// POST {"workflow": `{"name": "etl", "cronExpr": "0 2 * * *", "nodes": ["extract", "transform", "load"], "edges": [{"from": "extract", "to": "transform"}]}`}
+func handleSaveWorkflow(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 序列化工作流数据并校验
+	workflow := common.NewWorkflow()
+	if err := workflow.Unmarshal([]byte(r.PostForm.Get("workflow"))); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+	if err := workflow.Validate(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 保存工作流至 etcd 中
+	if err := GlobalManager.SaveWorkflow(workflow); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回成功响应
+	data, _ := response.Build(common.StateSuccess, "", nil)
+	_, _ = w.Write(data)
+}
+
+// handleDeleteWorkflow 删除工作流接口
+// POST {"name": "etl"}
+func handleDeleteWorkflow(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 从 etcd 中删除工作流
+	if err := GlobalManager.DeleteWorkflow(r.PostForm.Get("name")); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回成功响应
+	data, _ := response.Build(common.StateSuccess, "", nil)
+	_, _ = w.Write(data)
+}
+
+// handleListWorkflow 获取工作流列表接口
+// GET /workflow/list
+func handleListWorkflow(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 从 etcd 中获取工作流列表
+	workflowList, err := GlobalManager.ListWorkflow()
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回工作流列表响应
+	data, _ := response.Build(common.StateSuccess, "", workflowList)
+	_, _ = w.Write(data)
+}
+
+// handleRunWorkflow 手动执行工作流接口
+// POST {"name": "etl", "user": "admin"}
+func handleRunWorkflow(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 POST 表单
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 未指定触发用户时记录请求来源地址
+	user := r.PostForm.Get("user")
+	if user == "" {
+		user = r.RemoteAddr
+	}
+
+	// 创建工作流执行并通知 worker 服务执行根节点
+	run, err := GlobalManager.RunWorkflow(r.PostForm.Get("name"), user)
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回工作流执行响应
+	data, _ := response.Build(common.StateSuccess, "", run)
+	_, _ = w.Write(data)
+}
+
+// handleWorkflowRuns 获取工作流执行列表接口，包含各任务节点的执行状态
+// GET /workflow/runs?name=etl&limit=10
+func handleWorkflowRuns(w http.ResponseWriter, r *http.Request) {
+	// 实例化通讯响应对象
+	response := common.NewResponse()
+
+	// 解析 GET 参数
+	if err := r.ParseForm(); err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+	limit, err := strconv.Atoi(r.Form.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = 10
+	}
+
+	// 从 etcd 中获取工作流执行列表
+	runList, err := GlobalManager.ListWorkflowRun(r.Form.Get("name"), limit)
+	if err != nil {
+		data, _ := response.Build(common.StateFailure, err.Error(), nil)
+		_, _ = w.Write(data)
+		return
+	}
+
+	// 返回工作流执行列表响应
+	data, _ := response.Build(common.StateSuccess, "", runList)
+	_, _ = w.Write(data)
+}
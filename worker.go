@@ -2,6 +2,10 @@ package main
 
 import (
 	"log"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"crontab/worker"
 )
@@ -38,5 +42,26 @@ func main() {
 		log.Fatalln(err)
 	}
 
-	select {}
+	// 初始化工作流调度器
+	if err := worker.GlobalWorkflow.Init(); err != nil {
+		log.Fatalln(err)
+	}
+
+	// 等待退出信号
+	signalChan := make(chan os.Signal, 1)
+	signal.Notify(signalChan, syscall.SIGINT, syscall.SIGTERM)
+	<-signalChan
+
+	// 注销服务，不再执行新的任务
+	worker.GlobalRegister.Deregister()
+
+	// 等待正在执行的任务结束，超时后杀死任务
+	timeout := time.Duration(worker.GlobalConfig.ShutdownTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = 30 * time.Second
+	}
+	worker.GlobalScheduler.Stop(timeout)
+
+	// 提交尚未储存的日志
+	worker.GlobalLogger.Close()
 }
@@ -0,0 +1,114 @@
+package worker
+
+import (
+	"hash/fnv"
+	"time"
+
+	"crontab/common"
+)
+
+// AssignFallbackDelay 非执行节点等待执行节点认领调度的时间，超时未认领时由非执行节点兜底竞争执行
+const AssignFallbackDelay = 3 * time.Second
+
+// GlobalAssigner 任务分配器对象
+var GlobalAssigner = NewAssigner()
+
+// Assigner 任务分配器，在竞争分布式锁之前确定由哪个节点执行任务
+type Assigner struct{}
+
+// NewAssigner 实例化任务分配器对象
+func NewAssigner() *Assigner {
+	return &Assigner{}
+}
+
+// IsOwner 判断本节点是否为任务的执行节点
+func (a *Assigner) IsOwner(state *common.State) bool {
+	// 随机竞争策略、广播执行和补偿调度不分配执行节点，由分布式锁决定
+	strategy := GlobalConfig.AssignStrategy
+	if (strategy != common.AssignHash && strategy != common.AssignLeastLoaded) ||
+		state.Task.Mode == common.ModeBroadcast || state.CatchUp {
+		return true
+	}
+
+	// 候选节点: 支持任务类型、标签满足任务节点选择器、未达到最大负载且未在排空的在线节点
+	candidates := make([]*common.WorkerInfo, 0)
+	for _, info := range GlobalRegister.ListWorker() {
+		if !info.Draining && info.Supports(state.Task.GetType()) &&
+			state.Task.Selector.Matches(info.Labels) && info.Admits(state.Task.GetWeight()) {
+			candidates = append(candidates, info)
+		}
+	}
+
+	// 本节点尚未出现在在线节点列表中，交由分布式锁兜底
+	owner := a.pick(strategy, state.Task.Name, candidates)
+	if owner == nil || !a.isOnline(candidates) {
+		return true
+	}
+	return owner.ID == GlobalRegister.WorkerID
+}
+
+// Fallback 等待执行节点认领本次调度，各节点的在线节点列表不一致时可能没有节点认为自己是执行节点，
+// 超时未被认领时返回 true，由本节点兜底竞争执行
+func (a *Assigner) Fallback(state *common.State) bool {
+	select {
+	case <-time.After(AssignFallbackDelay):
+	case <-state.CancelCtx.Done():
+		return false
+	}
+	claimed, err := GlobalManager.IsTickClaimed(state.Task.Name, state.PlanTime)
+	return err == nil && !claimed
+}
+
+// Decline 本节点无法执行本次调度时，等待其他节点兜底竞争执行，超时仍未被认领时认领本次调度并返回 true，
+// 保证没有节点执行的调度只被记录一次，广播执行时每个节点各自记录
+func (a *Assigner) Decline(state *common.State) bool {
+	if state.Task.Mode == common.ModeBroadcast {
+		return true
+	}
+	select {
+	case <-time.After(2 * AssignFallbackDelay):
+	case <-state.CancelCtx.Done():
+		return false
+	}
+	claimed, err := GlobalManager.ClaimTick(state.Task.Name, state.PlanTime)
+	return err == nil && claimed
+}
+
+// pick 按照分配策略从候选节点中选出执行节点
+func (a *Assigner) pick(strategy string, taskName string, candidates []*common.WorkerInfo) *common.WorkerInfo {
+	var owner *common.WorkerInfo
+	var ownerScore uint64
+	for _, info := range candidates {
+		score := a.score(info.ID, taskName)
+		switch {
+		case owner == nil:
+		case strategy == common.AssignLeastLoaded && info.Load != owner.Load:
+			// 最小负载策略优先选择正在执行任务权重之和最小的节点
+			if info.Load > owner.Load {
+				continue
+			}
+		case score <= ownerScore:
+			// 一致性哈希(rendezvous hashing)选择得分最高的节点，节点增减时只影响少量任务
+			continue
+		}
+		owner, ownerScore = info, score
+	}
+	return owner
+}
+
+// score 计算节点与任务的哈希得分
+func (a *Assigner) score(worker string, taskName string) uint64 {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(worker + "/" + taskName))
+	return h.Sum64()
+}
+
+// isOnline 判断本节点是否在候选节点中
+func (a *Assigner) isOnline(candidates []*common.WorkerInfo) bool {
+	for _, info := range candidates {
+		if info.ID == GlobalRegister.WorkerID {
+			return true
+		}
+	}
+	return false
+}
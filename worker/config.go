@@ -10,14 +10,30 @@ var GlobalConfig = NewConfig()
 
 // Config 服务配置
 type Config struct {
-	BashPath              string   `json:"bashPath"`
-	ETCDEndpoints         []string `json:"etcdEndpoints"`
-	ETCDDialTimeout       int64    `json:"etcdDialTimeout"`
-	MongoDBURI            string   `json:"mongoDBURI"`
-	MongoDBConnectTimeout int64    `json:"mongoDBConnectTimeout"`
-	ChanSize              int      `json:"chanSize"`
-	BatchSize             int      `json:"batchSize"`
-	LogCommitTimeout      int      `json:"logCommitTimeout"`
+	BashPath                string            `json:"bashPath"`
+	ETCDEndpoints           []string          `json:"etcdEndpoints"`
+	ETCDDialTimeout         int64             `json:"etcdDialTimeout"`
+	MongoDBURI              string            `json:"mongoDBURI"`
+	MongoDBConnectTimeout   int64             `json:"mongoDBConnectTimeout"`
+	ChanSize                int               `json:"chanSize"`
+	BatchSize               int               `json:"batchSize"`
+	LogCommitTimeout        int               `json:"logCommitTimeout"`
+	MaxOutputSize           int               `json:"maxOutputSize"`
+	OutputDir               string            `json:"outputDir"`
+	OutputFileCount         int               `json:"outputFileCount"`
+	StreamInterval          int               `json:"streamInterval"`
+	Labels                  map[string]string `json:"labels"`
+	AssignStrategy          string            `json:"assignStrategy"`
+	MaxLoad                 int               `json:"maxLoad"`
+	HeartbeatInterval       int               `json:"heartbeatInterval"`
+	WorkerID                string            `json:"workerID"`
+	StateFile               string            `json:"stateFile"`
+	AdvertiseAddr           string            `json:"advertiseAddr"`
+	ShutdownTimeout         int               `json:"shutdownTimeout"`
+	CgroupRoot              string            `json:"cgroupRoot"`
+	DefaultUser             string            `json:"defaultUser"`
+	DenyRoot                bool              `json:"denyRoot"`
+	WorkflowDispatchTimeout int               `json:"workflowDispatchTimeout"`
 }
 
 // NewConfig 实例化服务配置对象
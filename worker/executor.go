@@ -1,8 +1,10 @@
 package worker
 
 import (
+	"context"
 	"math/rand"
-	"os/exec"
+	"strconv"
+	"sync/atomic"
 	"time"
 
 	"crontab/common"
@@ -12,7 +14,10 @@ import (
 var GlobalExecutor = NewExecutor()
 
 // Executor 任务执行器
-type Executor struct{}
+type Executor struct {
+	running int64 // 正在执行的任务数
+	load    int64 // 正在执行的任务权重之和
+}
 
 // NewExecutor 实例化任务执行对象
 func NewExecutor() *Executor {
@@ -22,38 +27,261 @@ func NewExecutor() *Executor {
 // ExecuteTask 并发执行任务
 func (e *Executor) ExecuteTask(state *common.State) {
 	go func() {
-		// 实例化任务执行结果对象
-		result := common.NewResult()
-		result.State = state
-
 		// 创建分布式锁
-		lock := GlobalManager.CreateLock(state.Task.Name)
-
-		// 上锁前随机睡眠，保证节点间均匀竞争执行任务的机会
-		time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
-
-		// 尝试上锁
-		err := lock.TryLock()
-		defer lock.UnLock()
-		if err != nil { // 上锁失败
-			result.StartTime = time.Now()
-			result.EndTime = time.Now()
-			result.Error = err
-		} else { // 上锁成功
-			// 记录任务开始执行时间
-			result.StartTime = time.Now()
-
-			// 执行 shell 命令
-			cmd := exec.CommandContext(state.CancelCtx, GlobalConfig.BashPath, "-c", state.Task.Shell)
-			output, err := cmd.Output()
-
-			// 记录任务结束执行时间、执行结果、执行错误
-			result.EndTime = time.Now()
-			result.Output = output
-			result.Error = err
-		}
-
-		// 推送执行执行结果到任务调度器
-		GlobalScheduler.PushResult(result)
+		lock := GlobalManager.CreateLock(e.lockKey(state))
+
+		// 释放分布式锁后推送最终执行结果，保证排队中的调度能够立即抢到锁
+		locked := false
+		finish := func(result *common.Result) {
+			lock.UnLock()
+			if locked {
+				e.release(state.Task, true)
+			}
+			GlobalScheduler.PushResult(result)
+		}
+
+		// 推进任务最近调度时间，未执行的调度同样视为已调度，避免被当作错过的调度补偿
+		e.claimMisfire(state)
+
+		// 任务已分配给其他节点，执行节点超时未认领时才兜底参与竞争
+		if !GlobalAssigner.IsOwner(state) {
+			state.Waiting.Store(true)
+			if !GlobalAssigner.Fallback(state) {
+				finish(e.abortResult(state, common.ErrorNotAssigned, common.StatusFailure))
+				return
+			}
+			state.Waiting.Store(false)
+		}
+
+		// 本节点正在排空，不参与竞争
+		if GlobalRegister.IsDraining() {
+			finish(e.abortResult(state, common.ErrorWorkerIsDraining, common.StatusFailure))
+			return
+		}
+
+		// 本节点已达到最大负载，不参与竞争，所有节点都已达到最大负载时由认领本次调度的节点记录跳过日志
+		if !e.reserve(state.Task) {
+			state.Waiting.Store(true)
+			if GlobalAssigner.Decline(state) {
+				finish(e.abortResult(state, common.ErrorAllWorkersBusy, common.StatusSkipped))
+				return
+			}
+			finish(e.abortResult(state, common.ErrorWorkerIsBusy, common.StatusFailure))
+			return
+		}
+
+		// 随机竞争策略下，上锁前随机睡眠，保证节点间均匀竞争执行任务的机会
+		if GlobalConfig.AssignStrategy != common.AssignHash && GlobalConfig.AssignStrategy != common.AssignLeastLoaded {
+			time.Sleep(time.Duration(rand.Intn(1000)) * time.Millisecond)
+		}
+
+		// 尝试上锁，分布式锁保证同一次调度只在一个节点上执行
+		if err := lock.TryLock(); err != nil { // 上锁失败
+			e.release(state.Task, false)
+			finish(e.abortResult(state, err, common.StatusFailure))
+			return
+		}
+		locked = true
+		_ = GlobalRegister.Publish()
+
+		// 认领本次调度，锁释放后其他节点不会再次执行同一次调度，广播执行每个节点各自执行
+		if state.Task.Mode != common.ModeBroadcast {
+			if claimed, err := GlobalManager.ClaimTick(state.Task.Name, state.PlanTime); err == nil && !claimed {
+				finish(e.abortResult(state, common.ErrorLockIsOccupied, common.StatusFailure))
+				return
+			}
+		}
+
+		// 记录认领工作流任务节点的 worker
+		if state.Trigger != nil && state.Trigger.RunID != "" {
+			go GlobalWorkflow.Dispatch(state, GlobalRegister.WorkerID)
+		}
+
+		// 允许并行执行时，检查集群内并行执行数是否已达上限
+		if state.Task.Concurrency == common.ConcurrencyAllow && state.Task.MaxParallel > 0 {
+			count, err := GlobalManager.CountLock(common.PathLock + state.Task.Name + "/")
+			if err == nil && count > int64(state.Task.MaxParallel) {
+				finish(e.abortResult(state, common.ErrorTooManyParallel, common.StatusSkipped))
+				return
+			}
+		}
+
+		// 上锁成功，执行任务，失败后按照重试策略在持有锁的节点上重新执行
+		for attempt := 1; ; attempt++ {
+			result := e.runAttempt(state, lock, attempt)
+
+			// 任务被强杀时不再重试
+			result.Retrying = result.Error != nil && state.CancelCtx.Err() == nil &&
+				state.Task.Retry.Allow(attempt, result.ExitCode)
+			if !result.Retrying {
+				finish(result)
+				return
+			}
+
+			// 推送执行执行结果到任务调度器
+			GlobalScheduler.PushResult(result)
+
+			// 退避等待后重试
+			select {
+			case <-time.After(state.Task.Retry.Delay(attempt)):
+			case <-state.CancelCtx.Done(): // 等待期间任务被强杀
+				result = e.abortResult(state, common.ErrorTaskKilled, common.StatusFailure)
+				result.Attempt = attempt + 1
+				result.Killed = true
+				finish(result)
+				return
+			}
+		}
 	}()
 }
+
+// RunningCount 获取正在执行的任务数
+func (e *Executor) RunningCount() int {
+	return int(atomic.LoadInt64(&e.running))
+}
+
+// Load 获取正在执行的任务权重之和
+func (e *Executor) Load() int {
+	return int(atomic.LoadInt64(&e.load))
+}
+
+// reserve 为任务预留负载，超出节点最大负载时返回 false
+func (e *Executor) reserve(task *common.Task) bool {
+	weight := int64(task.GetWeight())
+	for {
+		load := atomic.LoadInt64(&e.load)
+		if GlobalConfig.MaxLoad > 0 && load+weight > int64(GlobalConfig.MaxLoad) {
+			return false
+		}
+		if atomic.CompareAndSwapInt64(&e.load, load, load+weight) {
+			atomic.AddInt64(&e.running, 1)
+			return true
+		}
+	}
+}
+
+// release 释放任务预留的负载，publish 为 true 时发布到服务注册信息中
+func (e *Executor) release(task *common.Task, publish bool) {
+	atomic.AddInt64(&e.load, -int64(task.GetWeight()))
+	atomic.AddInt64(&e.running, -1)
+	if publish {
+		_ = GlobalRegister.Publish()
+	}
+}
+
+// lockKey 获取任务的分布式锁路径
+func (e *Executor) lockKey(state *common.State) string {
+	key := common.PathLock + state.Task.Name
+
+	// 广播执行时每个节点使用独立的锁
+	if state.Task.Mode == common.ModeBroadcast {
+		key += "/" + GlobalRegister.WorkerID
+	}
+
+	// 允许并行执行时每次调度使用独立的锁，仅保证同一次调度只在一个节点上执行
+	if state.Task.Concurrency == common.ConcurrencyAllow {
+		key += "/" + strconv.FormatInt(state.PlanTime.UnixNano()/1000/1000, 10)
+	}
+	return key
+}
+
+// claimMisfire 记录任务最近调度时间，并补偿上次调度之后错过的调度，补偿调度、手动执行和广播执行不记录
+func (e *Executor) claimMisfire(state *common.State) {
+	if state.CatchUp || state.Trigger != nil || state.Task.Mode == common.ModeBroadcast {
+		return
+	}
+	if missed, err := GlobalManager.ClaimMisfire(state.Task, state.PlanTime); err == nil && len(missed) != 0 {
+		event := common.NewEvent(common.EventMisfire, state.Task)
+		event.Missed = missed
+		GlobalScheduler.PushEvent(event)
+	}
+}
+
+// publishExecution 发布正在执行的任务，随分布式锁的租约释放而删除
+func (e *Executor) publishExecution(state *common.State, lock *Lock, result *common.Result, pid int) {
+	execution := common.NewExecution()
+	execution.ExecID = state.ID
+	execution.TaskName = state.Task.Name
+	execution.Worker = result.Worker
+	execution.Attempt = result.Attempt
+	execution.Pid = pid
+	execution.PlanTime = state.PlanTime.UnixMilli()
+	execution.StartTime = result.StartTime.UnixMilli()
+	_ = GlobalManager.PutExecution(execution, lock.LeaseID)
+}
+
+// abortResult 构建未执行命令的任务执行结果
+func (e *Executor) abortResult(state *common.State, err error, status string) *common.Result {
+	result := common.NewResult()
+	result.State = state
+	result.Worker = GlobalRegister.WorkerID
+	result.StartTime = time.Now()
+	result.EndTime = time.Now()
+	result.Error = err
+	result.Status = status
+	result.ExitCode = -1
+	return result
+}
+
+// runAttempt 按照任务类型选择任务执行器，执行一次任务
+func (e *Executor) runAttempt(state *common.State, lock *Lock, attempt int) *common.Result {
+	// 获取任务类型对应的任务执行器
+	runner, ok := GlobalRegistry.Get(state.Task.GetType())
+	if !ok {
+		result := e.abortResult(state, common.ErrorTaskTypeUnsupported, common.StatusFailure)
+		result.Attempt = attempt
+		return result
+	}
+
+	// 实例化任务执行结果对象
+	result := common.NewResult()
+	result.State = state
+	result.Worker = GlobalRegister.WorkerID
+	result.Attempt = attempt
+	result.ExitCode = -1
+
+	// 记录任务开始执行时间
+	result.StartTime = time.Now()
+
+	// 基于任务执行状态的上下文派生超时上下文，强杀和超时均可终止执行
+	ctx, cancel := context.WithCancel(state.CancelCtx)
+	defer cancel()
+	if state.Task.Timeout > 0 {
+		var timeoutCancel context.CancelFunc
+		ctx, timeoutCancel = context.WithTimeout(ctx, time.Duration(state.Task.Timeout)*time.Millisecond)
+		defer timeoutCancel()
+	}
+
+	// 发布正在执行的任务，在子进程中执行的任务在进程启动后发布
+	started := func(pid int) {
+		e.publishExecution(state, lock, result, pid)
+	}
+	if spawner, ok := runner.(Spawner); !ok || !spawner.Spawns() {
+		started(0)
+	}
+
+	// 执行任务，记录任务结束执行时间、执行错误
+	result.Error = runner.Run(ctx, state, result, started)
+	result.EndTime = time.Now()
+	result.Killed = ctx.Err() != nil
+
+	// 没有退出码的任务类型，执行成功时退出码为 0
+	if result.Error == nil && result.ExitCode < 0 {
+		result.ExitCode = 0
+	}
+
+	// 判断任务执行状态
+	switch {
+	case ctx.Err() == context.DeadlineExceeded: // 执行超时
+		result.Error = common.ErrorTaskTimeout
+		result.Status = common.StatusTimeout
+	case state.CancelCtx.Err() != nil: // 被强杀
+		result.Error = common.ErrorTaskKilled
+		result.Status = common.StatusFailure
+	case result.Error != nil: // 执行失败
+		result.Status = common.StatusFailure
+	default: // 执行成功
+		result.Status = common.StatusSuccess
+	}
+	return result
+}
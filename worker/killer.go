@@ -0,0 +1,78 @@
+package worker
+
+import (
+	"os/exec"
+	"sync"
+	"syscall"
+	"time"
+
+	"crontab/common"
+)
+
+// DefaultKillGrace 默认终止宽限期
+const DefaultKillGrace = 5 * time.Second
+
+// Killer 命令终止器，先向进程组发送 SIGTERM，宽限期后发送 SIGKILL
+type Killer struct {
+	Cmd    *exec.Cmd
+	Grace  time.Duration
+	signal syscall.Signal // 最近发送的信号
+	exited bool           // 命令是否已退出
+	mutex  sync.Mutex
+}
+
+// NewKiller 实例化命令终止器对象，命令在独立的进程组中执行，终止时连同子进程一起终止
+func NewKiller(cmd *exec.Cmd, task *common.Task) *Killer {
+	k := &Killer{
+		Cmd:   cmd,
+		Grace: killGrace(task),
+	}
+	setProcessGroup(cmd)
+	cmd.Cancel = k.Terminate
+
+	// 宽限期后仍有子进程占用输出管道时，不再等待输出
+	cmd.WaitDelay = k.Grace + time.Second
+	return k
+}
+
+// Terminate 向进程组发送 SIGTERM，宽限期后仍未退出时发送 SIGKILL
+func (k *Killer) Terminate() error {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+
+	k.signal = syscall.SIGTERM
+	time.AfterFunc(k.Grace, func() {
+		k.mutex.Lock()
+		defer k.mutex.Unlock()
+		if !k.exited {
+			k.signal = syscall.SIGKILL
+		}
+		_ = signalProcessGroup(k.Cmd, syscall.SIGKILL)
+	})
+	return signalProcessGroup(k.Cmd, syscall.SIGTERM)
+}
+
+// Stop 命令已退出，已发送 SIGTERM 时仍在宽限期后向进程组发送 SIGKILL，结束忽略 SIGTERM 的子进程
+func (k *Killer) Stop() {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	k.exited = true
+}
+
+// Signal 获取最近发送的信号，未发送时返回空字符串
+func (k *Killer) Signal() string {
+	k.mutex.Lock()
+	defer k.mutex.Unlock()
+	if k.signal == 0 {
+		return ""
+	}
+	return k.signal.String()
+}
+
+// killGrace 获取任务的终止宽限期
+func killGrace(task *common.Task) time.Duration {
+	if task.KillGrace <= 0 {
+		return DefaultKillGrace
+	}
+	return time.Duration(task.KillGrace) * time.Millisecond
+}
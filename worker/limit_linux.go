@@ -0,0 +1,155 @@
+//go:build linux
+
+package worker
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"golang.org/x/sys/unix"
+
+	"crontab/common"
+)
+
+// limitShimArg worker 作为包装进程执行时的参数，包装进程设置 rlimit 后 exec 任务命令
+// 参数格式: worker __crontab_rlimit <cpu> <nofile> <path> <argv...>
+const limitShimArg = "__crontab_rlimit"
+
+func init() {
+	if len(os.Args) > 5 && os.Args[1] == limitShimArg {
+		os.Exit(runLimitShim(os.Args[2:]))
+	}
+}
+
+// runLimitShim 设置 rlimit 后以任务命令替换包装进程，替换失败时返回退出码
+func runLimitShim(args []string) int {
+	rlimits := map[int]string{
+		unix.RLIMIT_CPU:    args[0],
+		unix.RLIMIT_NOFILE: args[1],
+	}
+	for resource, arg := range rlimits {
+		value, err := strconv.ParseUint(arg, 10, 64)
+		if err != nil || value == 0 {
+			continue
+		}
+		if err := unix.Setrlimit(resource, &unix.Rlimit{Cur: value, Max: value}); err != nil {
+			fmt.Fprintln(os.Stderr, "设置资源限制失败:", err)
+			return 126
+		}
+	}
+	err := syscall.Exec(args[2], args[3:], os.Environ())
+	fmt.Fprintln(os.Stderr, "执行命令失败:", err)
+	return 127
+}
+
+// cgroupHandle 本次执行使用的 cgroup
+type cgroupHandle struct {
+	path string   // cgroup 路径
+	dir  *os.File // cgroup 目录，命令进程通过 clone3 直接在 cgroup 中创建
+}
+
+// applyLimits 在命令启动前设置资源限制，命令进程从创建时起即处于限制中
+// 内存和进程数通过 cgroup v2 限制整个进程树，cgroup 不可用时返回错误，避免以虚拟内存或按用户统计的进程数近似限制
+// CPU 时间和打开文件数通过包装进程在 exec 任务命令前设置 rlimit，子进程继承限制，包装进程以任务执行用户运行，执行用户必须有权限执行 worker 程序
+func applyLimits(cmd *exec.Cmd, name string, limits *common.Limits) (*cgroupHandle, error) {
+	if limits == nil {
+		return nil, nil
+	}
+
+	// 通过包装进程设置 rlimit
+	if limits.CPUTime > 0 || limits.OpenFiles > 0 {
+		exe, err := os.Executable()
+		if err != nil {
+			return nil, err
+		}
+		args := []string{exe, limitShimArg, strconv.FormatInt(limits.CPUTime, 10), strconv.FormatInt(limits.OpenFiles, 10), cmd.Path}
+		cmd.Args = append(args, cmd.Args...)
+		cmd.Path = exe
+	}
+
+	// 在 cgroup 中创建命令进程
+	if limits.Memory <= 0 && limits.Processes <= 0 {
+		return nil, nil
+	}
+	path := createCgroup(name, limits)
+	if path == "" {
+		return nil, common.ErrorCgroupUnavailable
+	}
+	dir, err := os.Open(path)
+	if err != nil {
+		_ = os.Remove(path)
+		return nil, err
+	}
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.UseCgroupFD = true
+	cmd.SysProcAttr.CgroupFD = int(dir.Fd())
+	return &cgroupHandle{path: path, dir: dir}, nil
+}
+
+// releaseLimits 删除执行使用的 cgroup，返回 cgroup 的内存使用峰值，单位(byte)
+func releaseLimits(cgroup *cgroupHandle) int64 {
+	if cgroup == nil {
+		return 0
+	}
+	_ = cgroup.dir.Close()
+
+	// 读取内存使用峰值
+	var peak int64
+	if data, err := os.ReadFile(filepath.Join(cgroup.path, "memory.peak")); err == nil {
+		peak, _ = strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+	}
+
+	// 结束 cgroup 中残留的进程后删除 cgroup
+	if err := os.Remove(cgroup.path); err != nil {
+		_ = os.WriteFile(filepath.Join(cgroup.path, "cgroup.kill"), []byte("1"), 0644)
+		time.Sleep(100 * time.Millisecond)
+		_ = os.Remove(cgroup.path)
+	}
+	return peak
+}
+
+// createCgroup 创建本次执行使用的 cgroup 并写入资源限制，cgroup v2 不可写时返回空
+func createCgroup(name string, limits *common.Limits) string {
+	root := GlobalConfig.CgroupRoot
+	if root == "" {
+		return ""
+	}
+
+	// 为子 cgroup 启用内存和进程数控制器
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return ""
+	}
+	_ = os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte("+memory"), 0644)
+	_ = os.WriteFile(filepath.Join(root, "cgroup.subtree_control"), []byte("+pids"), 0644)
+
+	// 创建本次执行使用的 cgroup
+	cgroup := filepath.Join(root, name)
+	if err := os.Mkdir(cgroup, 0755); err != nil {
+		return ""
+	}
+
+	// 写入资源限制
+	files := make(map[string]string)
+	if limits.Memory > 0 {
+		files["memory.max"] = strconv.FormatInt(limits.Memory, 10)
+		files["memory.swap.max"] = "0"
+	}
+	if limits.Processes > 0 {
+		files["pids.max"] = strconv.FormatInt(limits.Processes, 10)
+	}
+	for file, value := range files {
+		if err := os.WriteFile(filepath.Join(cgroup, file), []byte(value), 0644); err != nil && file != "memory.swap.max" {
+			_ = os.Remove(cgroup)
+			return ""
+		}
+	}
+	return cgroup
+}
@@ -0,0 +1,25 @@
+//go:build !linux
+
+package worker
+
+import (
+	"os/exec"
+
+	"crontab/common"
+)
+
+// cgroupHandle 非 linux 系统不支持 cgroup
+type cgroupHandle struct{}
+
+// applyLimits 非 linux 系统不支持限制命令进程的资源，无法限制内存和进程数时返回错误
+func applyLimits(cmd *exec.Cmd, name string, limits *common.Limits) (*cgroupHandle, error) {
+	if limits != nil && (limits.Memory > 0 || limits.Processes > 0) {
+		return nil, common.ErrorCgroupUnavailable
+	}
+	return nil, nil
+}
+
+// releaseLimits 非 linux 系统不支持限制命令进程的资源
+func releaseLimits(cgroup *cgroupHandle) int64 {
+	return 0
+}
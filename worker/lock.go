@@ -10,7 +10,7 @@ import (
 
 // Lock 分布式锁
 type Lock struct {
-	TaskName string
+	Key      string // 分布式锁路径
 	KV       clientV3.KV
 	Lease    clientV3.Lease
 	LeaseID  clientV3.LeaseID
@@ -19,11 +19,11 @@ type Lock struct {
 }
 
 // NewLock 实例化分布式锁对象
-func NewLock(taskName string, kv clientV3.KV, lease clientV3.Lease) *Lock {
+func NewLock(key string, kv clientV3.KV, lease clientV3.Lease) *Lock {
 	return &Lock{
-		TaskName: taskName,
-		KV:       kv,
-		Lease:    lease,
+		Key:   key,
+		KV:    kv,
+		Lease: lease,
 	}
 }
 
@@ -59,10 +59,9 @@ func (l *Lock) TryLock() error {
 	txn := l.KV.Txn(context.TODO())
 
 	// 事务抢分布式锁
-	key := common.PathLock + l.TaskName
-	txn.If(clientV3.Compare(clientV3.CreateRevision(key), "=", 0)).
-		Then(clientV3.OpPut(key, "", clientV3.WithLease(grantResp.ID))).
-		Else(clientV3.OpGet(key))
+	txn.If(clientV3.Compare(clientV3.CreateRevision(l.Key), "=", 0)).
+		Then(clientV3.OpPut(l.Key, "", clientV3.WithLease(grantResp.ID))).
+		Else(clientV3.OpGet(l.Key))
 
 	// 提交事务
 	txnResp, err := txn.Commit()
@@ -92,5 +91,6 @@ func (l *Lock) UnLock() {
 	if l.isLocked {
 		l.Cancel()                                       // 取消自动续租
 		_, _ = l.Lease.Revoke(context.TODO(), l.LeaseID) // 释放租约
+		l.isLocked = false
 	}
 }
@@ -20,6 +20,7 @@ type Logger struct {
 	Collection *mongo.Collection
 	LogChan    chan *common.Log
 	BatchChan  chan *common.Batch
+	CloseChan  chan chan struct{}
 }
 
 // NewLogger 实例化日志管理器对象
@@ -43,6 +44,7 @@ func (l *Logger) Init() error {
 	l.Collection = client.Database("cron").Collection("log")
 	l.LogChan = make(chan *common.Log, GlobalConfig.ChanSize)
 	l.BatchChan = make(chan *common.Batch, GlobalConfig.ChanSize)
+	l.CloseChan = make(chan chan struct{})
 
 	// 启动日志储存协程
 	go l.WriteLoop()
@@ -59,6 +61,13 @@ func (l *Logger) Save(log *common.Log) {
 	}
 }
 
+// Close 提交尚未储存的日志并停止日志储存协程
+func (l *Logger) Close() {
+	done := make(chan struct{})
+	l.CloseChan <- done
+	<-done
+}
+
 // WriteLoop 日志储存协程
 func (l *Logger) WriteLoop() {
 	var batch *common.Batch
@@ -110,6 +119,25 @@ func (l *Logger) WriteLoop() {
 
 			// 清空日志批次
 			batch = nil
+		case done := <-l.CloseChan: // 停止日志储存
+			// 将日志通道中剩余的日志追加到日志批次中
+			if batch == nil {
+				batch = common.NewBatch()
+			} else {
+				commitTimer.Stop()
+			}
+			for len(l.LogChan) != 0 {
+				batch.Logs = append(batch.Logs, <-l.LogChan)
+			}
+
+			// 将日志批次写入 mongodb 中
+			if len(batch.Logs) != 0 {
+				if _, err := l.Collection.InsertMany(context.TODO(), batch.Logs); err != nil {
+					fmt.Println(err)
+				}
+			}
+			close(done)
+			return
 		}
 	}
 }
@@ -2,8 +2,13 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/gorhill/cronexpr"
 	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientV3 "go.etcd.io/etcd/client/v3"
 
@@ -15,15 +20,19 @@ var GlobalManager = NewManager()
 
 // Manager 任务管理器
 type Manager struct {
-	Client  *clientV3.Client
-	KV      clientV3.KV
-	Lease   clientV3.Lease
-	Watcher clientV3.Watcher
+	Client    *clientV3.Client
+	KV        clientV3.KV
+	Lease     clientV3.Lease
+	Watcher   clientV3.Watcher
+	tailTable map[string]string // 实时输出订阅者，订阅标记路径为键，任务名称为值
+	tailMutex sync.RWMutex
 }
 
 // NewManager 实例化任务管理对象
 func NewManager() *Manager {
-	return &Manager{}
+	return &Manager{
+		tailTable: make(map[string]string),
+	}
 }
 
 // Init 初始化任务管理对象
@@ -54,6 +63,19 @@ func (m *Manager) Init() error {
 	// 监听 etcd 中杀死任务变化事件
 	go m.WatchKill()
 
+	// 监听 etcd 中手动执行任务变化事件
+	go m.WatchRun()
+
+	// 监听 etcd 中本节点排空变化
+	if err := m.WatchDrain(); err != nil {
+		return err
+	}
+
+	// 监听 etcd 中实时输出订阅者变化
+	if err := m.WatchTail(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -75,6 +97,16 @@ func (m *Manager) WatchTask() error {
 
 		// 推送监听事件到任务调度器
 		GlobalScheduler.PushEvent(event)
+
+		// 认领服务停机期间错过的调度，最近的调度留给正常调度中的节点处理，未配置 cron 表达式的任务没有错过的调度
+		if task.CronExpr == "" || !task.Selector.Matches(GlobalConfig.Labels) || task.Mode == common.ModeBroadcast {
+			continue
+		}
+		if missed, err := m.ClaimMisfire(task, time.Now().Add(-5*time.Second)); err == nil && len(missed) != 0 {
+			event := common.NewEvent(common.EventMisfire, task)
+			event.Missed = missed
+			GlobalScheduler.PushEvent(event)
+		}
 	}
 
 	// 监听 etcd 中任务变化事件
@@ -98,6 +130,7 @@ func (m *Manager) WatchKill() {
 				task := common.NewTask()
 				task.Name = common.ExtractName(string(e.Kv.Key), common.PathKill)
 				event := common.NewEvent(common.EventKill, task)
+				event.ExecID = string(e.Kv.Value)
 				// 推送监听事件到任务调度器
 				GlobalScheduler.PushEvent(event)
 			case mvccpb.DELETE: // kill 标记过期，被自动删除
@@ -107,6 +140,119 @@ func (m *Manager) WatchKill() {
 	}
 }
 
+// WatchRun 监听 etcd 中手动执行任务变化
+func (m *Manager) WatchRun() {
+	// 监听任务变化事件
+	watchChan := m.Watcher.Watch(context.TODO(), common.PathRun, clientV3.WithPrefix())
+
+	// 处理监听事件
+	for resp := range watchChan {
+
+		// 遍历监听事件列表，依次反序列化
+		for _, e := range resp.Events {
+			switch e.Type {
+			case mvccpb.PUT: // 手动执行任务事件
+				trigger := common.NewTrigger()
+				if err := trigger.Unmarshal(e.Kv.Value); err != nil {
+					continue
+				}
+				task := common.NewTask()
+				task.Name = common.ExtractName(string(e.Kv.Key), common.PathRun)
+				event := common.NewEvent(common.EventRun, task)
+				event.Trigger = trigger
+				// 推送监听事件到任务调度器
+				GlobalScheduler.PushEvent(event)
+			case mvccpb.DELETE: // run 标记过期，被自动删除
+
+			}
+		}
+	}
+}
+
+// WatchDrain 监听 etcd 中本节点排空变化
+func (m *Manager) WatchDrain() error {
+	key := common.PathDrain + GlobalRegister.WorkerID
+
+	// 获取本节点排空标记
+	resp, err := m.KV.Get(context.TODO(), key, clientV3.WithCountOnly())
+	if err != nil {
+		return err
+	}
+	if resp.Count != 0 {
+		GlobalRegister.SetDraining(true)
+	}
+
+	// 监听排空标记变化事件
+	go func() {
+		watchChan := m.Watcher.Watch(context.TODO(), key, clientV3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchChan {
+			for _, e := range watchResp.Events {
+				switch e.Type {
+				case mvccpb.PUT: // 进入排空
+					GlobalRegister.SetDraining(true)
+				case mvccpb.DELETE: // 取消排空
+					GlobalRegister.SetDraining(false)
+				}
+			}
+		}
+	}()
+
+	return nil
+}
+
+// WatchTail 监听 etcd 中实时输出订阅者变化
+func (m *Manager) WatchTail() error {
+	// 获取实时输出订阅者列表
+	resp, err := m.KV.Get(context.TODO(), common.PathTail, clientV3.WithPrefix(), clientV3.WithKeysOnly())
+	if err != nil {
+		return err
+	}
+	m.tailMutex.Lock()
+	for _, kv := range resp.Kvs {
+		m.putTail(string(kv.Key))
+	}
+	m.tailMutex.Unlock()
+
+	// 监听订阅者变化事件
+	go func() {
+		watchChan := m.Watcher.Watch(context.TODO(), common.PathTail, clientV3.WithPrefix(), clientV3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchChan {
+			m.tailMutex.Lock()
+			for _, e := range watchResp.Events {
+				switch e.Type {
+				case mvccpb.PUT: // 订阅实时输出
+					m.putTail(string(e.Kv.Key))
+				case mvccpb.DELETE: // 取消订阅或订阅标记过期
+					delete(m.tailTable, string(e.Kv.Key))
+				}
+			}
+			m.tailMutex.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// putTail 记录实时输出订阅者，订阅标记路径为 /cron/tail/任务名称/订阅编号
+func (m *Manager) putTail(key string) {
+	name := common.ExtractName(key, common.PathTail)
+	if i := strings.LastIndex(name, "/"); i >= 0 {
+		m.tailTable[key] = name[:i]
+	}
+}
+
+// IsTailed 判断任务是否有实时输出订阅者
+func (m *Manager) IsTailed(name string) bool {
+	m.tailMutex.RLock()
+	defer m.tailMutex.RUnlock()
+	for _, task := range m.tailTable {
+		if task == name {
+			return true
+		}
+	}
+	return false
+}
+
 // watchEvent 监听 etcd 中任务变化事件
 func (m *Manager) watchEvent(revision int64) {
 	// 监听任务变化事件
@@ -138,6 +284,100 @@ func (m *Manager) watchEvent(revision int64) {
 }
 
 // CreateLock 创建分布式锁
-func (m *Manager) CreateLock(taskName string) *Lock {
-	return NewLock(taskName, m.KV, m.Lease)
+func (m *Manager) CreateLock(key string) *Lock {
+	return NewLock(key, m.KV, m.Lease)
+}
+
+// ClaimMisfire 将任务最近调度时间推进至 fireTime，并认领期间错过的调度
+func (m *Manager) ClaimMisfire(task *common.Task, fireTime time.Time) ([]time.Time, error) {
+	// 获取任务最近调度时间
+	key := common.PathFire + task.Name
+	resp, err := m.KV.Get(context.TODO(), key)
+	if err != nil {
+		return nil, err
+	}
+	var lastTime time.Time
+	var revision int64
+	if len(resp.Kvs) != 0 {
+		if ms, err := strconv.ParseInt(string(resp.Kvs[0].Value), 10, 64); err == nil {
+			lastTime = time.UnixMilli(ms)
+		}
+		revision = resp.Kvs[0].ModRevision
+	}
+
+	// 补偿调度的时间早于最近调度时间，无需推进
+	if !fireTime.After(lastTime) {
+		return nil, nil
+	}
+
+	// 事务推进最近调度时间，保证错过的调度只被一个节点认领
+	txnResp, err := m.KV.Txn(context.TODO()).
+		If(clientV3.Compare(clientV3.ModRevision(key), "=", revision)).
+		Then(clientV3.OpPut(key, strconv.FormatInt(fireTime.UnixMilli(), 10))).
+		Commit()
+	if err != nil {
+		return nil, err
+	}
+	if !txnResp.Succeeded || lastTime.IsZero() {
+		return nil, nil
+	}
+
+	// 计算错过的调度时间
+	expr, err := cronexpr.Parse(task.CronExpr)
+	if err != nil {
+		return nil, err
+	}
+	return task.Misfire.Missed(expr, lastTime, fireTime), nil
+}
+
+// ClaimTick 认领任务的一次调度，保证同一次调度只被一个节点执行，已被其他节点认领时返回 false
+func (m *Manager) ClaimTick(name string, planTime time.Time) (bool, error) {
+	// 创建租约，认领标记过期后自动删除
+	leaseResp, err := m.Lease.Grant(context.TODO(), common.ClaimTTL)
+	if err != nil {
+		return false, err
+	}
+
+	// 事务创建认领标记，已被其他节点认领时撤销租约
+	key := common.PathClaim + name + "/" + strconv.FormatInt(planTime.UnixMilli(), 10)
+	txnResp, err := m.KV.Txn(context.TODO()).
+		If(clientV3.Compare(clientV3.CreateRevision(key), "=", 0)).
+		Then(clientV3.OpPut(key, GlobalRegister.WorkerID, clientV3.WithLease(leaseResp.ID))).
+		Commit()
+	if err != nil || !txnResp.Succeeded {
+		_, _ = m.Lease.Revoke(context.TODO(), leaseResp.ID)
+		return false, err
+	}
+	return true, nil
+}
+
+// IsTickClaimed 判断任务的一次调度是否已被认领
+func (m *Manager) IsTickClaimed(name string, planTime time.Time) (bool, error) {
+	key := common.PathClaim + name + "/" + strconv.FormatInt(planTime.UnixMilli(), 10)
+	resp, err := m.KV.Get(context.TODO(), key, clientV3.WithCountOnly())
+	if err != nil {
+		return false, err
+	}
+	return resp.Count != 0, nil
+}
+
+// PutExecution 发布正在执行的任务
+func (m *Manager) PutExecution(execution *common.Execution, leaseID clientV3.LeaseID) error {
+	value, err := json.Marshal(execution)
+	if err != nil {
+		return err
+	}
+
+	key := common.PathRunning + execution.TaskName + "/" + execution.ExecID
+	_, err = m.KV.Put(context.TODO(), key, string(value), clientV3.WithLease(leaseID))
+	return err
+}
+
+// CountLock 统计指定路径下已被占用的分布式锁数量
+func (m *Manager) CountLock(prefix string) (int64, error) {
+	resp, err := m.KV.Get(context.TODO(), prefix, clientV3.WithPrefix(), clientV3.WithCountOnly())
+	if err != nil {
+		return 0, err
+	}
+	return resp.Count, nil
 }
@@ -0,0 +1,191 @@
+package worker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"crontab/common"
+)
+
+// 命令输出捕获上限
+const (
+	// DefaultOutputLimit 默认捕获上限，单位(byte)
+	DefaultOutputLimit = 64 * 1024
+
+	// MaxOutputLimit 最大捕获上限，保证日志不超过 mongodb 单个文档 16MB 的限制，单位(byte)
+	MaxOutputLimit = 4 * 1024 * 1024
+)
+
+// 正在写入的溢出文件，轮转时不删除
+var (
+	openFiles     = make(map[string]bool)
+	openFileMutex sync.Mutex
+)
+
+// Output 有界的命令输出缓冲区，超出上限时只保留头部和尾部
+type Output struct {
+	Limit int      // 捕获上限
+	Total int64    // 输出总字节数
+	File  *os.File // 完整输出的溢出文件，为空表示不溢出
+	head  []byte   // 头部输出
+	tail  []byte   // 尾部输出
+}
+
+// NewOutput 实例化命令输出缓冲区对象
+func NewOutput(limit int, file *os.File) *Output {
+	return &Output{
+		Limit: limit,
+		File:  file,
+	}
+}
+
+// Write 写入命令输出
+func (o *Output) Write(p []byte) (int, error) {
+	n := len(p)
+	o.Total += int64(n)
+
+	// 写入溢出文件
+	if o.File != nil {
+		_, _ = o.File.Write(p)
+	}
+
+	// 优先写入头部
+	headLimit := o.Limit - o.Limit/2
+	if size := headLimit - len(o.head); size > 0 {
+		if size > len(p) {
+			size = len(p)
+		}
+		o.head = append(o.head, p[:size]...)
+		p = p[size:]
+	}
+
+	// 写入尾部，超过两倍尾部上限时丢弃较早的输出
+	tailLimit := o.Limit / 2
+	o.tail = append(o.tail, p...)
+	if len(o.tail) > 2*tailLimit {
+		o.tail = append(o.tail[:0:0], o.tail[len(o.tail)-tailLimit:]...)
+	}
+
+	return n, nil
+}
+
+// Truncated 判断命令输出是否被截断
+func (o *Output) Truncated() bool {
+	return o.Total > int64(o.Limit)
+}
+
+// Bytes 获取捕获的命令输出，被截断时在头部和尾部之间插入截断标记
+func (o *Output) Bytes() []byte {
+	if !o.Truncated() {
+		return append(append([]byte{}, o.head...), o.tail...)
+	}
+
+	tail := o.tail[len(o.tail)-o.Limit/2:]
+	marker := fmt.Sprintf("\n...[已截断 %d 字节]...\n", o.Total-int64(len(o.head))-int64(len(tail)))
+	data := make([]byte, 0, len(o.head)+len(marker)+len(tail))
+	data = append(data, o.head...)
+	data = append(data, marker...)
+	return append(data, tail...)
+}
+
+// outputLimit 获取任务的命令输出捕获上限
+func outputLimit(task *common.Task) int {
+	limit := task.OutputLimit
+	if limit <= 0 {
+		limit = GlobalConfig.MaxOutputSize
+	}
+	if limit <= 0 {
+		limit = DefaultOutputLimit
+	}
+	if limit > MaxOutputLimit {
+		limit = MaxOutputLimit
+	}
+	return limit
+}
+
+// createOutputFile 创建保存完整命令输出的溢出文件，未配置溢出目录时返回空
+func createOutputFile(state *common.State, attempt int) *os.File {
+	if GlobalConfig.OutputDir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(GlobalConfig.OutputDir, 0755); err != nil {
+		return nil
+	}
+
+	// 文件名: 任务名称-执行编号-执行次数.log
+	name := state.Task.Name + "-" + state.ID + "-" + strconv.Itoa(attempt) + ".log"
+	file, err := os.Create(filepath.Join(GlobalConfig.OutputDir, filepath.Base(name)))
+	if err != nil {
+		return nil
+	}
+
+	// 记录正在写入的溢出文件
+	openFileMutex.Lock()
+	openFiles[filepath.Base(file.Name())] = true
+	openFileMutex.Unlock()
+	return file
+}
+
+// closeOutputFile 关闭溢出文件，输出未被截断时删除文件，返回保留的文件路径
+func closeOutputFile(file *os.File, truncated bool) string {
+	if file == nil {
+		return ""
+	}
+	_ = file.Close()
+
+	openFileMutex.Lock()
+	defer openFileMutex.Unlock()
+	delete(openFiles, filepath.Base(file.Name()))
+
+	// 输出已完整保存在日志中，无需保留溢出文件
+	if !truncated {
+		_ = os.Remove(file.Name())
+		return ""
+	}
+
+	// 以关闭时间作为修改时间，长时间没有输出的文件不会在轮转时被当作较早的文件删除
+	now := time.Now()
+	_ = os.Chtimes(file.Name(), now, now)
+
+	// 轮转溢出文件
+	rotateOutputFiles()
+	return file.Name()
+}
+
+// rotateOutputFiles 只保留最近的溢出文件，删除较早的溢出文件，正在写入的溢出文件不参与轮转，调用时需持有 openFileMutex
+func rotateOutputFiles() {
+	if GlobalConfig.OutputFileCount <= 0 {
+		return
+	}
+
+	// 获取所有溢出文件
+	entries, err := os.ReadDir(GlobalConfig.OutputDir)
+	if err != nil {
+		return
+	}
+	files := make([]os.FileInfo, 0, len(entries))
+	for _, entry := range entries {
+		if openFiles[entry.Name()] {
+			continue
+		}
+		if info, err := entry.Info(); err == nil && info.Mode().IsRegular() {
+			files = append(files, info)
+		}
+	}
+	if len(files) <= GlobalConfig.OutputFileCount {
+		return
+	}
+
+	// 按照修改时间倒序排序，删除超出数量的较早文件
+	sort.Slice(files, func(i, j int) bool {
+		return files[i].ModTime().After(files[j].ModTime())
+	})
+	for _, info := range files[GlobalConfig.OutputFileCount:] {
+		_ = os.Remove(filepath.Join(GlobalConfig.OutputDir, info.Name()))
+	}
+}
@@ -0,0 +1,110 @@
+//go:build !windows
+
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"os/user"
+	"runtime"
+	"strconv"
+	"syscall"
+
+	"crontab/common"
+)
+
+// setProcessGroup 命令在独立的进程组中执行
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Setpgid = true
+}
+
+// signalProcessGroup 向命令所在的进程组发送信号
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return syscall.Kill(-cmd.Process.Pid, sig)
+}
+
+// setCredential 以任务指定的用户执行命令，worker 不是 root 用户时只能以自身用户执行
+// worker 以 root 用户运行且任务未指定用户时，配置了默认用户则以默认用户执行，开启 denyRoot 时拒绝执行，否则以 root 用户执行
+func setCredential(cmd *exec.Cmd, task *common.Task) error {
+	if task.UID == nil {
+		if os.Geteuid() != 0 || (GlobalConfig.DefaultUser == "" && !GlobalConfig.DenyRoot) {
+			return nil
+		}
+		uid, gid, err := defaultCredential()
+		if err != nil {
+			return err
+		}
+		if cmd.SysProcAttr == nil {
+			cmd.SysProcAttr = &syscall.SysProcAttr{}
+		}
+		cmd.SysProcAttr.Credential = &syscall.Credential{Uid: uid, Gid: gid, NoSetGroups: true}
+		return nil
+	}
+	gid := *task.UID
+	if task.GID != nil {
+		gid = *task.GID
+	}
+
+	// 非 root 用户无法切换用户，指定的用户与 worker 相同时直接执行
+	if os.Geteuid() != 0 {
+		if *task.UID == os.Geteuid() && gid == os.Getegid() {
+			return nil
+		}
+		return common.ErrorRunAsDenied
+	}
+
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.Credential = &syscall.Credential{
+		Uid:         uint32(*task.UID),
+		Gid:         uint32(gid),
+		NoSetGroups: true,
+	}
+	return nil
+}
+
+// defaultCredential 获取配置的默认用户的 uid 和 gid，默认用户可以是用户名或 uid，不能是 root 用户
+func defaultCredential() (uint32, uint32, error) {
+	if GlobalConfig.DefaultUser == "" {
+		return 0, 0, common.ErrorRunAsRootDenied
+	}
+	u, err := user.Lookup(GlobalConfig.DefaultUser)
+	if err != nil {
+		if u, err = user.LookupId(GlobalConfig.DefaultUser); err != nil {
+			return 0, 0, err
+		}
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return 0, 0, err
+	}
+	if uid == 0 || gid == 0 {
+		return 0, 0, common.ErrorRunAsRootDenied
+	}
+	return uint32(uid), uint32(gid), nil
+}
+
+// processMaxRSS 获取已退出进程的内存使用峰值，单位(byte)
+func processMaxRSS(state *os.ProcessState) int64 {
+	rusage, ok := state.SysUsage().(*syscall.Rusage)
+	if !ok {
+		return 0
+	}
+
+	// darwin 的单位为 byte，其他系统的单位为 KB
+	if runtime.GOOS == "darwin" {
+		return int64(rusage.Maxrss)
+	}
+	return int64(rusage.Maxrss) * 1024
+}
@@ -0,0 +1,40 @@
+//go:build windows
+
+package worker
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+
+	"crontab/common"
+)
+
+// setProcessGroup 在独立的进程组中执行命令
+func setProcessGroup(cmd *exec.Cmd) {
+	if cmd.SysProcAttr == nil {
+		cmd.SysProcAttr = &syscall.SysProcAttr{}
+	}
+	cmd.SysProcAttr.CreationFlags |= syscall.CREATE_NEW_PROCESS_GROUP
+}
+
+// signalProcessGroup windows 不支持向进程组发送信号，直接结束命令进程
+func signalProcessGroup(cmd *exec.Cmd, sig syscall.Signal) error {
+	if cmd.Process == nil {
+		return nil
+	}
+	return cmd.Process.Kill()
+}
+
+// setCredential windows 不支持切换任务执行用户
+func setCredential(cmd *exec.Cmd, task *common.Task) error {
+	if task.UID != nil {
+		return common.ErrorRunAsNotSupported
+	}
+	return nil
+}
+
+// processMaxRSS windows 不记录进程的内存使用峰值
+func processMaxRSS(state *os.ProcessState) int64 {
+	return 0
+}
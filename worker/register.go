@@ -2,9 +2,15 @@ package worker
 
 import (
 	"context"
+	"encoding/json"
 	"net"
+	"os"
+	"runtime"
+	"strings"
+	"sync"
 	"time"
 
+	"go.etcd.io/etcd/api/v3/mvccpb"
 	clientV3 "go.etcd.io/etcd/client/v3"
 
 	"crontab/common"
@@ -15,15 +21,25 @@ var GlobalRegister = NewRegister()
 
 // Register 服务注册
 type Register struct {
-	Client  *clientV3.Client
-	KV      clientV3.KV
-	Lease   clientV3.Lease
-	LocalIP string
+	Client    *clientV3.Client
+	KV        clientV3.KV
+	Lease     clientV3.Lease
+	Watcher   clientV3.Watcher
+	WorkerID  string // 节点编号
+	Addr      string // 节点对外通告地址
+	StartTime time.Time
+	leaseID   clientV3.LeaseID              // 当前注册使用的租约
+	draining  bool                          // 是否正在排空
+	closed    bool                          // 是否已注销
+	workers   map[string]*common.WorkerInfo // 在线节点表
+	mutex     sync.RWMutex
 }
 
 // NewRegister 实例化服务注册对象
 func NewRegister() *Register {
-	return &Register{}
+	return &Register{
+		workers: make(map[string]*common.WorkerInfo),
+	}
 }
 
 // Init 初始化服务注册对象
@@ -40,25 +56,82 @@ func (r *Register) Init() error {
 		return err
 	}
 
-	// 获取本机 IPV4 地址
-	localIP, err := r.GetLocalIP()
+	// 获取节点编号
+	workerID, err := r.GetWorkerID()
 	if err != nil {
 		return err
 	}
 
+	// 获取节点对外通告地址
+	addr := GlobalConfig.AdvertiseAddr
+	if addr == "" {
+		if addr, err = r.GetLocalIP(); err != nil {
+			return err
+		}
+	}
+
 	// 服务注册对象赋值
 	r.Client = client
 	r.KV = clientV3.NewKV(client)
 	r.Lease = clientV3.NewLease(client)
-	r.LocalIP = localIP
+	r.Watcher = clientV3.NewWatcher(client)
+	r.WorkerID = workerID
+	r.Addr = addr
+	r.StartTime = time.Now()
 
 	// 注册服务并自动续租
 	go r.KeepOnline()
 
+	// 定期发布心跳
+	go r.HeartbeatLoop()
+
+	// 监听在线节点变化
+	if err := r.WatchWorker(); err != nil {
+		return err
+	}
+
 	return nil
 }
 
-// GetLocalIP 获取本机 IPV4 地址
+// GetWorkerID 获取节点编号，未配置时首次启动自动生成并保存到状态文件
+func (r *Register) GetWorkerID() (string, error) {
+	workerID := GlobalConfig.WorkerID
+	switch workerID {
+	case "hostname": // 使用主机名
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		workerID = hostname
+	case "": // 读取状态文件中保存的节点编号
+		stateFile := GlobalConfig.StateFile
+		if stateFile == "" {
+			stateFile = "./worker.id"
+		}
+		data, err := os.ReadFile(stateFile)
+		if err == nil {
+			workerID = strings.TrimSpace(string(data))
+			break
+		}
+		if !os.IsNotExist(err) {
+			return "", err
+		}
+
+		// 首次启动，生成节点编号并保存到状态文件
+		workerID = common.NewID()
+		if err := os.WriteFile(stateFile, []byte(workerID+"\n"), 0644); err != nil {
+			return "", err
+		}
+	}
+
+	// 节点编号用作 etcd 路径的一部分
+	if workerID == "" || strings.Contains(workerID, "/") {
+		return "", common.ErrorInvalidWorkerID
+	}
+	return workerID, nil
+}
+
+// GetLocalIP 获取本机 IP 地址，优先 IPV4，没有时使用 IPV6
 func (r *Register) GetLocalIP() (string, error) {
 	// 获取所有网卡信息
 	address, err := net.InterfaceAddrs()
@@ -66,11 +139,22 @@ func (r *Register) GetLocalIP() (string, error) {
 		return "", err
 	}
 
-	// 获取第一个非环回地址的 ipv4 地址
+	// 获取第一个非环回地址的 ipv4 地址，同时记录第一个全局单播的 ipv6 地址
+	ipv6 := ""
 	for _, addr := range address {
-		if ipNet, ok := addr.(*net.IPNet); ok && !ipNet.IP.IsLoopback() && ipNet.IP.To4() != nil {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ipNet.IP.To4() != nil {
 			return ipNet.IP.String(), nil
 		}
+		if ipv6 == "" && ipNet.IP.IsGlobalUnicast() {
+			ipv6 = ipNet.IP.String()
+		}
+	}
+	if ipv6 != "" {
+		return ipv6, nil
 	}
 
 	return "", common.ErrorNoLocalIPFound
@@ -86,7 +170,7 @@ func (r *Register) KeepOnline() {
 		}
 	}
 
-	for {
+	for !r.isClosed() {
 		// 创建租约
 		grantResp, err := r.Lease.Grant(context.TODO(), 10)
 		if err != nil {
@@ -94,19 +178,31 @@ func (r *Register) KeepOnline() {
 			continue
 		}
 
+		// 创建上下文
+		ctx, cancel := context.WithCancel(context.TODO())
+
 		// 自动续租
-		keepAliveChan, err := r.Lease.KeepAlive(context.TODO(), grantResp.ID)
+		keepAliveChan, err := r.Lease.KeepAlive(ctx, grantResp.ID)
 		if err != nil {
-			rollback(nil)
+			rollback(cancel)
 			continue
 		}
 
-		// 创建上下文
-		ctx, cancel := context.WithCancel(context.TODO())
-
-		// 将本机 IP 注册到 etcd
-		if _, err := r.KV.Put(ctx, common.PathWorker+r.LocalIP, "", clientV3.WithLease(grantResp.ID)); err != nil {
+		// 将本机注册信息发布到 etcd，服务已注销时撤销新创建的租约
+		r.mutex.Lock()
+		closed := r.closed
+		if !closed {
+			r.leaseID = grantResp.ID
+		}
+		r.mutex.Unlock()
+		if closed {
+			_, _ = r.Lease.Revoke(context.TODO(), grantResp.ID)
+			cancel()
+			return
+		}
+		if err := r.Publish(); err != nil {
 			rollback(cancel)
+			continue
 		}
 
 		// 处理自动续租应答
@@ -119,3 +215,151 @@ func (r *Register) KeepOnline() {
 		rollback(cancel)
 	}
 }
+
+// Publish 发布本机注册信息，注册信息变化时调用
+func (r *Register) Publish() error {
+	r.mutex.RLock()
+	leaseID := r.leaseID
+	draining := r.draining
+	r.mutex.RUnlock()
+
+	// 尚未创建租约
+	if leaseID == clientV3.NoLease {
+		return nil
+	}
+
+	// 构建本机注册信息
+	info := common.NewWorkerInfo()
+	info.ID = r.WorkerID
+	info.Addr = r.Addr
+	info.Hostname, _ = os.Hostname()
+	info.Pid = os.Getpid()
+	info.Version = common.Version
+	info.OS = runtime.GOOS
+	info.Arch = runtime.GOARCH
+	info.NumCPU = runtime.NumCPU()
+	info.Labels = GlobalConfig.Labels
+	info.Types = GlobalRegistry.Types()
+	info.Running = GlobalExecutor.RunningCount()
+	info.Load = GlobalExecutor.Load()
+	info.Capacity = GlobalConfig.MaxLoad
+	info.Draining = draining
+	info.StartTime = r.StartTime.UnixMilli()
+	info.LastHeartbeat = time.Now().UnixMilli()
+	value, err := json.Marshal(info)
+	if err != nil {
+		return err
+	}
+
+	// 将本机注册信息注册到 etcd
+	_, err = r.KV.Put(context.TODO(), common.PathWorker+r.WorkerID, string(value), clientV3.WithLease(leaseID))
+	return err
+}
+
+// HeartbeatLoop 心跳协程，定期刷新本机注册信息
+func (r *Register) HeartbeatLoop() {
+	interval := time.Duration(GlobalConfig.HeartbeatInterval) * time.Millisecond
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if r.isClosed() {
+			return
+		}
+		_ = r.Publish()
+	}
+}
+
+// SetDraining 设置节点排空状态，排空中的节点不再执行新的任务
+func (r *Register) SetDraining(draining bool) {
+	r.mutex.Lock()
+	r.draining = draining
+	r.mutex.Unlock()
+	_ = r.Publish()
+}
+
+// IsDraining 判断节点是否正在排空
+func (r *Register) IsDraining() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.draining
+}
+
+// Deregister 注销服务，撤销租约立即删除本机注册信息，并不再执行新的任务
+func (r *Register) Deregister() {
+	r.mutex.Lock()
+	r.draining = true
+	r.closed = true
+	leaseID := r.leaseID
+	r.leaseID = clientV3.NoLease
+	r.mutex.Unlock()
+
+	if leaseID != clientV3.NoLease {
+		_, _ = r.Lease.Revoke(context.TODO(), leaseID)
+	}
+}
+
+// isClosed 判断服务是否已注销
+func (r *Register) isClosed() bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	return r.closed
+}
+
+// WatchWorker 监听 etcd 中在线节点变化
+func (r *Register) WatchWorker() error {
+	// 获取在线节点列表
+	resp, err := r.KV.Get(context.TODO(), common.PathWorker, clientV3.WithPrefix())
+	if err != nil {
+		return err
+	}
+
+	// 遍历在线节点列表，依次反序列化
+	r.mutex.Lock()
+	for _, kv := range resp.Kvs {
+		r.putWorker(kv)
+	}
+	r.mutex.Unlock()
+
+	// 监听在线节点变化事件
+	go func() {
+		watchChan := r.Watcher.Watch(context.TODO(), common.PathWorker, clientV3.WithPrefix(), clientV3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchChan {
+			r.mutex.Lock()
+			for _, e := range watchResp.Events {
+				switch e.Type {
+				case mvccpb.PUT: // 节点上线或注册信息变化
+					r.putWorker(e.Kv)
+				case mvccpb.DELETE: // 节点下线
+					delete(r.workers, common.ExtractName(string(e.Kv.Key), common.PathWorker))
+				}
+			}
+			r.mutex.Unlock()
+		}
+	}()
+
+	return nil
+}
+
+// ListWorker 获取在线节点列表
+func (r *Register) ListWorker() []*common.WorkerInfo {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	workerList := make([]*common.WorkerInfo, 0, len(r.workers))
+	for _, info := range r.workers {
+		workerList = append(workerList, info)
+	}
+	return workerList
+}
+
+// putWorker 保存在线节点，调用方需持有写锁
+func (r *Register) putWorker(kv *mvccpb.KeyValue) {
+	info := common.NewWorkerInfo()
+	_ = info.Unmarshal(kv.Value)
+	info.ID = common.ExtractName(string(kv.Key), common.PathWorker)
+	r.workers[info.ID] = info
+}
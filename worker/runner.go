@@ -0,0 +1,90 @@
+package worker
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"crontab/common"
+)
+
+// GlobalRegistry 任务执行器注册表对象
+var GlobalRegistry = NewRegistry()
+
+// Runner 任务执行器，每种任务类型对应一个实现
+type Runner interface {
+	// Run 执行一次任务，ctx 在任务超时或被强杀时取消
+	// 输出、退出码等执行信息写入 result，返回执行错误
+	// 在子进程中执行任务的执行器在进程启动后调用 started 传入进程 pid，其他执行器忽略 started
+	Run(ctx context.Context, state *common.State, result *common.Result, started func(pid int)) error
+}
+
+// Availability 任务执行器可选实现的接口，用于判断本节点是否具备执行条件
+type Availability interface {
+	Available() bool
+}
+
+// Spawner 任务执行器可选实现的接口，在子进程中执行任务时返回 true，正在执行的任务在进程启动后发布
+type Spawner interface {
+	Spawns() bool
+}
+
+// Registry 任务执行器注册表，以任务类型为键
+type Registry struct {
+	runners map[string]Runner
+	mutex   sync.RWMutex
+}
+
+// NewRegistry 实例化任务执行器注册表对象，并注册内置的任务执行器
+func NewRegistry() *Registry {
+	r := &Registry{
+		runners: make(map[string]Runner),
+	}
+	r.Register(common.TaskTypeShell, &CommandRunner{})
+	r.Register(common.TaskTypeExec, &CommandRunner{})
+	r.Register(common.TaskTypeHTTP, &HTTPRunner{})
+	r.Register(common.TaskTypeSQL, &SQLRunner{})
+	r.Register(common.TaskTypeGRPC, &GRPCRunner{})
+	return r
+}
+
+// Register 注册任务执行器，已存在时覆盖
+func (r *Registry) Register(taskType string, runner Runner) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	r.runners[taskType] = runner
+}
+
+// Get 获取任务类型对应的任务执行器，本节点不具备执行条件时返回 false
+func (r *Registry) Get(taskType string) (Runner, bool) {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+	runner, ok := r.runners[taskType]
+	if !ok {
+		return nil, false
+	}
+	if availability, ok := runner.(Availability); ok && !availability.Available() {
+		return nil, false
+	}
+	return runner, true
+}
+
+// Types 获取本节点支持的任务类型列表，发布到服务注册信息中
+func (r *Registry) Types() []string {
+	r.mutex.RLock()
+	taskTypes := make([]string, 0, len(r.runners))
+	for taskType := range r.runners {
+		taskTypes = append(taskTypes, taskType)
+	}
+	r.mutex.RUnlock()
+
+	// 过滤不具备执行条件的任务类型
+	supported := make([]string, 0, len(taskTypes))
+	for _, taskType := range taskTypes {
+		if _, ok := r.Get(taskType); ok {
+			supported = append(supported, taskType)
+		}
+	}
+	sort.Strings(supported)
+	return supported
+}
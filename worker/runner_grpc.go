@@ -0,0 +1,56 @@
+package worker
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	healthPB "google.golang.org/grpc/health/grpc_health_v1"
+
+	"crontab/common"
+)
+
+// DefaultProbeTimeout grpc 健康检查和 http 请求未配置超时时间时的默认超时时间
+const DefaultProbeTimeout = 30 * time.Second
+
+// GRPCRunner grpc 健康检查执行器，执行 grpc 类型的任务
+type GRPCRunner struct{}
+
+// Run 执行一次 grpc 健康检查
+func (g *GRPCRunner) Run(ctx context.Context, state *common.State, result *common.Result, _ func(pid int)) error {
+	// 连接 grpc 服务
+	probe := state.Task.GRPC
+	if err := probe.Validate(); err != nil {
+		return err
+	}
+	// 未配置执行超时时间时使用默认超时时间，避免阻塞连接时一直持有分布式锁
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, DefaultProbeTimeout)
+		defer cancel()
+	}
+	creds := insecure.NewCredentials()
+	if probe.TLS {
+		creds = credentials.NewTLS(&tls.Config{})
+	}
+	conn, err := grpc.DialContext(ctx, probe.Target, grpc.WithTransportCredentials(creds), grpc.WithBlock())
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	// 执行健康检查，服务状态为 SERVING 时成功
+	resp, err := healthPB.NewHealthClient(conn).Check(ctx, &healthPB.HealthCheckRequest{Service: probe.Service})
+	if err != nil {
+		return err
+	}
+	result.Stdout = []byte(resp.GetStatus().String())
+	if resp.GetStatus() != healthPB.HealthCheckResponse_SERVING {
+		return fmt.Errorf("%w: %s", common.ErrorGRPCNotServing, resp.GetStatus())
+	}
+	return nil
+}
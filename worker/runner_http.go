@@ -0,0 +1,61 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"crontab/common"
+)
+
+// HTTPRunner http 请求执行器，执行 http 类型的任务
+type HTTPRunner struct{}
+
+// Run 执行一次 http 请求
+func (h *HTTPRunner) Run(ctx context.Context, state *common.State, result *common.Result, _ func(pid int)) error {
+	// 构建 http 请求
+	request := state.Task.HTTP
+	if err := request.Validate(); err != nil {
+		return err
+	}
+
+	// 请求超时时间，避免请求一直阻塞时持有分布式锁
+	timeout := DefaultProbeTimeout
+	if request.Timeout > 0 {
+		timeout = time.Duration(request.Timeout) * time.Millisecond
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, request.GetMethod(), request.URL, strings.NewReader(request.Body))
+	if err != nil {
+		return err
+	}
+	for key, value := range request.Headers {
+		req.Header.Set(key, value)
+	}
+
+	// 发送请求，记录从发送请求到收到响应头的耗时
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	result.Latency = time.Since(start).Milliseconds()
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// 捕获有界的响应体
+	body := NewOutput(outputLimit(state.Task), nil)
+	_, err = io.Copy(body, resp.Body)
+	result.StatusCode = resp.StatusCode
+	result.Stdout = body.Bytes()
+	result.Truncated = body.Truncated()
+	if err != nil {
+		return err
+	}
+
+	// 按照响应状态码和响应体判断请求是否成功
+	return request.Check(resp.StatusCode, result.Stdout)
+}
@@ -0,0 +1,142 @@
+package worker
+
+import (
+	"context"
+	"io"
+	"os"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"syscall"
+
+	"crontab/common"
+)
+
+// CommandRunner 命令执行器，执行 shell 和 exec 类型的任务
+type CommandRunner struct{}
+
+// Spawns 命令在子进程中执行
+func (c *CommandRunner) Spawns() bool {
+	return true
+}
+
+// Run 执行一次任务命令
+func (c *CommandRunner) Run(ctx context.Context, state *common.State, result *common.Result, started func(pid int)) error {
+	// 构建任务命令
+	cmd, err := c.buildCommand(ctx, state, result)
+	if err != nil {
+		return err
+	}
+
+	// 执行任务命令，分别捕获有界的标准输出和标准错误输出
+	file := createOutputFile(state, result.Attempt)
+	stdout := NewOutput(outputLimit(state.Task), file)
+	stderr := NewOutput(outputLimit(state.Task), file)
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+
+	// 终止任务时先发送 SIGTERM 给整个进程组，宽限期后发送 SIGKILL
+	killer := NewKiller(cmd, state.Task)
+
+	// 推送运行中任务的实时输出
+	stream := NewStream(state, result.Attempt)
+	if stream != nil {
+		cmd.Stdout = io.MultiWriter(stdout, stream.Stdout())
+		cmd.Stderr = io.MultiWriter(stderr, stream.Stderr())
+	}
+
+	// 设置命令进程的资源限制，命令进程启动时即处于限制中
+	cgroup, err := applyLimits(cmd, state.ID+"-"+strconv.Itoa(result.Attempt), state.Task.Limits)
+	if err == nil {
+		err = cmd.Start()
+	}
+	if err == nil {
+		// 发布正在执行的任务
+		started(cmd.Process.Pid)
+
+		err = cmd.Wait()
+		killer.Stop()
+	}
+	result.MaxRSS = releaseLimits(cgroup)
+	if stream != nil {
+		stream.Close()
+	}
+
+	// 记录执行输出、退出码
+	result.Stdout = stdout.Bytes()
+	result.Stderr = stderr.Bytes()
+	result.Truncated = stdout.Truncated() || stderr.Truncated()
+	result.OutputFile = closeOutputFile(file, result.Truncated)
+	if cmd.ProcessState != nil {
+		result.ExitCode = cmd.ProcessState.ExitCode()
+
+		// 记录内存使用峰值和 CPU 时间
+		if maxRSS := processMaxRSS(cmd.ProcessState); maxRSS > result.MaxRSS {
+			result.MaxRSS = maxRSS
+		}
+		result.CPUTime = (cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()).Milliseconds()
+
+		// 记录终止进程的信号
+		if status, ok := cmd.ProcessState.Sys().(syscall.WaitStatus); ok && status.Signaled() {
+			result.Signal = status.Signal().String()
+		}
+	}
+
+	// 命令捕获了信号后自行退出时，记录发送给进程组的信号
+	if ctx.Err() != nil && result.Signal == "" {
+		result.Signal = killer.Signal()
+	}
+	return err
+}
+
+// buildCommand 按照任务类型构建任务命令，shell 模板渲染后的命令记录到执行结果中
+func (c *CommandRunner) buildCommand(ctx context.Context, state *common.State, result *common.Result) (*exec.Cmd, error) {
+	task := state.Task
+	var cmd *exec.Cmd
+	switch task.Type {
+	case common.TaskTypeExec: // exec 类型不经过 shell 直接执行命令及参数
+		if len(task.Args) == 0 || task.Args[0] == "" {
+			return nil, common.ErrorTaskArgsEmpty
+		}
+		cmd = exec.CommandContext(ctx, task.Args[0], task.Args[1:]...)
+
+		// 写入标准输入内容
+		if task.Stdin != "" {
+			cmd.Stdin = strings.NewReader(task.Stdin)
+		}
+	default: // shell 类型通过 bash -c 执行，开启模板时先渲染模板
+		shell := task.Shell
+		if task.IsTemplate() {
+			vars, err := common.NewTemplateVars(state, result.Worker, result.Attempt)
+			if err != nil {
+				return nil, err
+			}
+			if shell, err = task.Render(vars); err != nil {
+				return nil, err
+			}
+			result.Command = shell
+		}
+		cmd = exec.CommandContext(ctx, GlobalConfig.BashPath, "-c", shell)
+	}
+	cmd.Dir = task.Dir
+
+	// 在 worker 的环境变量基础上追加任务的环境变量
+	if len(task.Env) != 0 {
+		keys := make([]string, 0, len(task.Env))
+		for key := range task.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		cmd.Env = os.Environ()
+		for _, key := range keys {
+			cmd.Env = append(cmd.Env, key+"="+task.Env[key])
+		}
+	}
+
+	// 切换任务执行用户
+	if err := setCredential(cmd, task); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
@@ -0,0 +1,44 @@
+package worker
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+
+	_ "github.com/go-sql-driver/mysql" // mysql 驱动
+	_ "github.com/lib/pq"              // postgres 驱动
+
+	"crontab/common"
+)
+
+// SQLRunner sql 执行器，执行 sql 类型的任务，支持 mysql 和 postgres 驱动
+type SQLRunner struct{}
+
+// Available 判断是否已引入 database/sql 驱动
+func (s *SQLRunner) Available() bool {
+	return len(sql.Drivers()) != 0
+}
+
+// Run 执行一次 sql 语句
+func (s *SQLRunner) Run(ctx context.Context, state *common.State, result *common.Result, _ func(pid int)) error {
+	// 连接数据库
+	query := state.Task.SQL
+	if err := query.Validate(); err != nil {
+		return err
+	}
+	db, err := sql.Open(query.Driver, query.DSN)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	// 执行 sql 语句，记录影响行数
+	res, err := db.ExecContext(ctx, query.Query)
+	if err != nil {
+		return err
+	}
+	if rows, err := res.RowsAffected(); err == nil {
+		result.Stdout = []byte("影响行数: " + strconv.FormatInt(rows, 10))
+	}
+	return nil
+}
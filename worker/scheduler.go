@@ -12,19 +12,28 @@ var GlobalScheduler = NewScheduler()
 
 // Scheduler 任务调度器
 type Scheduler struct {
-	PlanTable  map[string]*common.Plan  // 任务调度计划表
-	StateTable map[string]*common.State // 任务执行状态表
-	EventChan  chan *common.Event       // 监听事件通道
-	ResultChan chan *common.Result      // 任务执行结果通道
+	PlanTable    map[string]*common.Plan  // 任务调度计划表
+	StateTable   map[string]*common.State // 任务执行状态表，以执行编号为键
+	PendingTable map[string]*common.Plan  // 排队等待执行的任务调度计划表
+	EventChan    chan *common.Event       // 监听事件通道
+	ResultChan   chan *common.Result      // 任务执行结果通道
+	StopChan     chan bool                // 停止调度通道，为 true 时杀死正在执行的任务
+	GraceChan    chan time.Duration       // 杀死正在执行的任务后，返回任务中最长的终止宽限期
+	DoneChan     chan struct{}            // 调度协程退出通道
+	stopping     bool                     // 是否正在停止调度
 }
 
 // NewScheduler 实例化任务调度器
 func NewScheduler() *Scheduler {
 	return &Scheduler{
-		PlanTable:  make(map[string]*common.Plan),
-		StateTable: make(map[string]*common.State),
-		EventChan:  make(chan *common.Event, GlobalConfig.ChanSize),
-		ResultChan: make(chan *common.Result, GlobalConfig.ChanSize),
+		PlanTable:    make(map[string]*common.Plan),
+		StateTable:   make(map[string]*common.State),
+		PendingTable: make(map[string]*common.Plan),
+		EventChan:    make(chan *common.Event, GlobalConfig.ChanSize),
+		ResultChan:   make(chan *common.Result, GlobalConfig.ChanSize),
+		StopChan:     make(chan bool, 1),
+		GraceChan:    make(chan time.Duration, 1),
+		DoneChan:     make(chan struct{}),
 	}
 }
 
@@ -44,6 +53,29 @@ func (s *Scheduler) PushResult(result *common.Result) {
 	s.ResultChan <- result
 }
 
+// Stop 停止调度新的任务并等待正在执行的任务结束，超时后杀死正在执行的任务
+func (s *Scheduler) Stop(timeout time.Duration) {
+	s.StopChan <- false
+	select {
+	case <-s.DoneChan:
+		return
+	case <-time.After(timeout):
+	}
+
+	// 等待超时，杀死正在执行的任务后再等待任务中最长的终止宽限期，保证宽限期后的 SIGKILL 能够发出
+	s.StopChan <- true
+	var grace time.Duration
+	select {
+	case <-s.DoneChan:
+		return
+	case grace = <-s.GraceChan:
+	}
+	select {
+	case <-s.DoneChan:
+	case <-time.After(grace + 5*time.Second):
+	}
+}
+
 // handleEvent 增删改内存中维护的任务列表
 func (s *Scheduler) handleEvent(event *common.Event) error {
 	switch event.Type {
@@ -53,14 +85,42 @@ func (s *Scheduler) handleEvent(event *common.Event) error {
 		if err := plan.Build(event.Task); err != nil {
 			return err
 		}
+		// 保留等待补偿的调度时间，暂停的任务不再补偿
+		if old, ok := s.PlanTable[event.Task.Name]; ok && !event.Task.Disabled {
+			plan.Missed = old.Missed
+		}
 		// 保存任务调度计划
 		s.PlanTable[event.Task.Name] = plan
 	case common.EventDelete: // 删除任务事件
 		delete(s.PlanTable, event.Task.Name)
+		delete(s.PendingTable, event.Task.Name)
 	case common.EventKill: //杀死任务事件
-		// 判断任务是否正在执行中
-		if state, ok := s.StateTable[event.Task.Name]; ok {
-			state.CancelFunc()
+		// 只杀死指定的执行
+		if event.ExecID != "" {
+			if state, ok := s.StateTable[event.ExecID]; ok && state.Task.Name == event.Task.Name {
+				state.CancelFunc()
+			}
+			break
+		}
+		// 杀死正在执行和等待认领中的任务，并清除排队中的调度
+		for _, state := range s.StateTable {
+			if state.Task.Name == event.Task.Name {
+				state.CancelFunc()
+			}
+		}
+		delete(s.PendingTable, event.Task.Name)
+	case common.EventMisfire: // 错过调度事件
+		if plan, ok := s.PlanTable[event.Task.Name]; ok && !plan.Task.Disabled {
+			plan.Missed = append(plan.Missed, event.Missed...)
+		}
+	case common.EventRun: // 手动执行事件
+		if plan, ok := s.PlanTable[event.Task.Name]; ok {
+			// 以触发时间作为调度时间，脱离 cron 调度执行一次
+			manual := *plan
+			manual.NextTime = time.UnixMilli(event.Trigger.Time)
+			manual.Missed = nil
+			manual.Trigger = event.Trigger
+			s.handlePlan(&manual)
 		}
 	}
 	return nil
@@ -68,54 +128,176 @@ func (s *Scheduler) handleEvent(event *common.Event) error {
 
 // handleResult 处理任务执行结果
 func (s *Scheduler) handleResult(result *common.Result) {
-	// 删除任务执行状态
-	delete(s.StateTable, result.State.Task.Name)
+	// 补偿调度未抢到锁时，说明任务正在其他节点执行
+	if result.State.CatchUp && result.Error == common.ErrorLockIsOccupied {
+		result.Error = common.ErrorTaskIsRunning
+		result.Status = common.StatusSkipped
+	}
 
-	// 实例化任务执行日志对象
-	if result.Error != common.ErrorLockIsOccupied {
-		taskLog := &common.Log{
-			TaskName:  result.State.Task.Name,
-			Command:   result.State.Task.Shell,
-			Output:    string(result.Output),
-			PlanTime:  result.State.PlanTime.UnixNano() / 1000 / 1000,
-			RealTime:  result.State.RealTime.UnixNano() / 1000 / 1000,
-			StartTime: result.StartTime.UnixNano() / 1000 / 1000,
-			EndTime:   result.EndTime.UnixNano() / 1000 / 1000,
-		}
-		if result.Error != nil {
-			taskLog.Error = result.Error.Error()
-		} else {
-			taskLog.Error = ""
-		}
+	// 被新的调度替换的任务
+	if result.State.Replaced && result.Status != common.StatusSuccess {
+		result.Error = common.ErrorTaskReplaced
+		result.Status = common.StatusReplaced
+	}
+
+	// 实例化任务执行日志对象，未参与执行的节点不记录日志
+	silent := result.Error == common.ErrorLockIsOccupied || result.Error == common.ErrorNotAssigned ||
+		result.Error == common.ErrorWorkerIsBusy || result.Error == common.ErrorWorkerIsDraining
+	if !silent {
+		taskLog := common.NewLog()
+		taskLog.Build(result)
 
 		// 将日志储存到 mongodb
 		GlobalLogger.Save(taskLog)
 	}
+
+	// 任务还将重试，继续保留任务执行状态
+	if result.Retrying {
+		return
+	}
+
+	// 删除任务执行状态
+	delete(s.StateTable, result.State.ID)
+
+	// 工作流触发的任务执行结束后，更新工作流执行状态并触发下游任务
+	if trigger := result.State.Trigger; !silent && trigger != nil && trigger.RunID != "" {
+		go GlobalWorkflow.Complete(result)
+	}
+
+	// 任务执行结束后，执行排队中的调度
+	name := result.State.Task.Name
+	if plan, ok := s.PendingTable[name]; ok && len(s.runningStates(name)) == 0 {
+		delete(s.PendingTable, name)
+		s.startPlan(plan)
+	}
 }
 
 // handlePlan 处理任务调度计划
 func (s *Scheduler) handlePlan(plan *common.Plan) {
+	// 正在停止调度，不再执行新的任务
+	if s.stopping {
+		return
+	}
+
+	// 节点标签不满足任务的节点选择器，不参与执行
+	if !plan.Task.Selector.Matches(GlobalConfig.Labels) {
+		return
+	}
+
+	// 本节点不支持任务类型，不参与执行
+	if _, ok := GlobalRegistry.Get(plan.Task.GetType()); !ok {
+		return
+	}
+
 	// 判断任务是否正在执行
-	if _, ok := s.StateTable[plan.Task.Name]; ok {
-		fmt.Println("任务正在执行:", plan.Task.Name)
+	running := s.runningStates(plan.Task.Name)
+	if len(running) == 0 {
+		s.startPlan(plan)
 		return
 	}
 
+	// 任务正在执行，按照并发策略处理本次调度
+	pending := *plan
+	switch plan.Task.Concurrency {
+	case common.ConcurrencyAllow: // 允许并行执行
+		s.startPlan(plan)
+	case common.ConcurrencyQueue: // 排队等待一次调度
+		if _, ok := s.PendingTable[plan.Task.Name]; ok {
+			s.skipPlan(plan, common.ErrorTaskIsQueued)
+			return
+		}
+		s.PendingTable[plan.Task.Name] = &pending
+	case common.ConcurrencyReplace: // 杀死正在执行的任务，结束后执行本次调度
+		if old, ok := s.PendingTable[plan.Task.Name]; ok {
+			s.skipPlan(old, common.ErrorTaskReplaced)
+		}
+		for _, state := range running {
+			state.Replaced = true
+			state.CancelFunc()
+		}
+		s.PendingTable[plan.Task.Name] = &pending
+	default: // 跳过本次调度
+		s.skipPlan(plan, common.ErrorTaskIsRunning)
+	}
+}
+
+// startPlan 开始执行任务调度计划
+func (s *Scheduler) startPlan(plan *common.Plan) {
 	// 构建任务执行状态对象
 	state := common.NewState()
 	state.Build(plan)
+	s.runState(state)
+}
 
+// catchUpPlan 补偿一次错过的调度
+func (s *Scheduler) catchUpPlan(plan *common.Plan) {
+	// 取出最早错过的调度时间
+	missed := *plan
+	missed.NextTime = plan.Missed[0]
+	plan.Missed = plan.Missed[1:]
+
+	// 构建任务执行状态对象
+	state := common.NewState()
+	state.Build(&missed)
+	state.CatchUp = true
+	s.runState(state)
+}
+
+// runState 保存任务执行状态并执行任务
+func (s *Scheduler) runState(state *common.State) {
 	// 保存任务执行状态
-	s.StateTable[state.Task.Name] = state
+	s.StateTable[state.ID] = state
 
 	fmt.Println("开始执行任务", state.Task.Name, state.PlanTime, state.RealTime)
 	GlobalExecutor.ExecuteTask(state)
 }
 
+// skipPlan 记录被跳过的任务调度计划
+func (s *Scheduler) skipPlan(plan *common.Plan, err error) {
+	// 构建任务执行状态对象
+	state := common.NewState()
+	state.Build(plan)
+	state.CancelFunc()
+
+	// 被跳过的调度同样推进任务最近调度时间
+	go GlobalExecutor.claimMisfire(state)
+
+	// 构建任务执行结果对象
+	result := common.NewResult()
+	result.State = state
+	result.Worker = GlobalRegister.WorkerID
+	result.StartTime = state.RealTime
+	result.EndTime = state.RealTime
+	result.Error = err
+	result.Status = common.StatusSkipped
+	result.ExitCode = -1
+
+	// 将日志储存到 mongodb
+	taskLog := common.NewLog()
+	taskLog.Build(result)
+	GlobalLogger.Save(taskLog)
+
+	// 工作流触发的任务被跳过时，同样更新工作流执行状态并触发下游任务
+	if plan.Trigger != nil && plan.Trigger.RunID != "" {
+		go GlobalWorkflow.Complete(result)
+	}
+}
+
+// runningStates 获取任务正在执行中的执行状态列表，不包含等待其他节点认领的调度
+func (s *Scheduler) runningStates(name string) []*common.State {
+	states := make([]*common.State, 0)
+	for _, state := range s.StateTable {
+		if state.Task.Name == name && !state.Waiting.Load() {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
 // Schedule 计算任务调度状态
 func (s *Scheduler) schedule() time.Duration {
-	// 判断任务调度计划表是否为空
-	if len(s.PlanTable) == 0 {
+	// 判断任务调度计划表是否为空，正在停止调度时不再调度任务
+	if len(s.PlanTable) == 0 || s.stopping {
 		return 1 * time.Second
 	}
 
@@ -125,10 +307,22 @@ func (s *Scheduler) schedule() time.Duration {
 	// 遍历所有任务
 	var nearTime *time.Time
 	for _, plan := range s.PlanTable {
+		// 未配置 cron 表达式的任务不参与 cron 调度
+		if plan.Expr == nil {
+			continue
+		}
+
+		// 逐个补偿错过的调度，上一次补偿执行结束后再执行下一次
+		if len(plan.Missed) != 0 && len(s.runningStates(plan.Task.Name)) == 0 {
+			s.catchUpPlan(plan)
+		}
+
 		// 判断任务是否需要执行
 		if plan.NextTime.Before(now) || plan.NextTime.Equal(now) {
-			// 执行任务调度计划
-			s.handlePlan(plan)
+			// 执行任务调度计划，暂停的任务仅推进调度时间
+			if !plan.Task.Disabled {
+				s.handlePlan(plan)
+			}
 			// 更新下次调度时间
 			plan.NextTime = plan.Expr.Next(now)
 		}
@@ -139,6 +333,9 @@ func (s *Scheduler) schedule() time.Duration {
 	}
 
 	// 返回下次调度间隔
+	if nearTime == nil {
+		return 1 * time.Second
+	}
 	return (*nearTime).Sub(now)
 }
 
@@ -156,6 +353,25 @@ func (s *Scheduler) scheduleLoop() {
 		case <-timer.C: // 最近需要执行的任务到期
 		case result := <-s.ResultChan: // 监听任务执行结果
 			s.handleResult(result)
+		case kill := <-s.StopChan: // 停止调度
+			s.stopping = true
+			s.PendingTable = make(map[string]*common.Plan)
+			if kill {
+				grace := DefaultKillGrace
+				for _, state := range s.StateTable {
+					state.CancelFunc()
+					if g := killGrace(state.Task); g > grace {
+						grace = g
+					}
+				}
+				s.GraceChan <- grace
+			}
+		}
+
+		// 停止调度后，所有任务执行结束时退出调度协程
+		if s.stopping && len(s.StateTable) == 0 {
+			close(s.DoneChan)
+			return
 		}
 
 		// 计算任务调度状态
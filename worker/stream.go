@@ -0,0 +1,152 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+
+	clientV3 "go.etcd.io/etcd/client/v3"
+
+	"crontab/common"
+)
+
+// 实时输出片段的存活时间，单位(s)
+const streamTTL = 60
+
+// 单个实时输出片段的最大字节数
+const streamChunkLimit = 64 * 1024
+
+// Stream 任务实时输出推送器，有订阅者时定期将运行中任务的输出推送到 etcd
+type Stream struct {
+	State   *common.State
+	Attempt int
+	LeaseID clientV3.LeaseID
+	mutex   sync.Mutex
+	stdout  []byte
+	stderr  []byte
+	seq     int
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewStream 实例化并启动任务实时输出推送器，未配置推送间隔时返回空
+func NewStream(state *common.State, attempt int) *Stream {
+	if GlobalConfig.StreamInterval <= 0 {
+		return nil
+	}
+
+	s := &Stream{
+		State:   state,
+		Attempt: attempt,
+		done:    make(chan struct{}),
+	}
+
+	// 启动推送协程
+	s.wg.Add(1)
+	go s.pushLoop()
+
+	return s
+}
+
+// Stdout 获取标准输出的写入器
+func (s *Stream) Stdout() *StreamWriter {
+	return &StreamWriter{stream: s}
+}
+
+// Stderr 获取标准错误输出的写入器
+func (s *Stream) Stderr() *StreamWriter {
+	return &StreamWriter{stream: s, stderr: true}
+}
+
+// Close 推送剩余输出并停止推送协程
+func (s *Stream) Close() {
+	close(s.done)
+	s.wg.Wait()
+}
+
+// pushLoop 推送协程
+func (s *Stream) pushLoop() {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(time.Duration(GlobalConfig.StreamInterval) * time.Millisecond)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.push(false)
+		case <-s.done:
+			s.push(true)
+			return
+		}
+	}
+}
+
+// push 推送一个实时输出片段
+func (s *Stream) push(eof bool) {
+	// 取出缓冲的输出
+	s.mutex.Lock()
+	stdout, stderr := s.stdout, s.stderr
+	s.stdout, s.stderr = nil, nil
+	s.mutex.Unlock()
+
+	// 没有新的输出，或没有订阅者时丢弃输出
+	if len(stdout) == 0 && len(stderr) == 0 && !eof {
+		return
+	}
+	if !GlobalManager.IsTailed(s.State.Task.Name) {
+		return
+	}
+
+	// 首次推送时创建租约，实时输出片段过期后自动删除
+	if s.LeaseID == clientV3.NoLease {
+		grantResp, err := GlobalManager.Lease.Grant(context.TODO(), streamTTL)
+		if err != nil {
+			return
+		}
+		s.LeaseID = grantResp.ID
+	}
+
+	// 构建实时输出片段
+	s.seq++
+	chunk := common.NewChunk()
+	chunk.ExecID = s.State.ID
+	chunk.TaskName = s.State.Task.Name
+	chunk.Attempt = s.Attempt
+	chunk.Seq = s.seq
+	chunk.Stdout = string(stdout)
+	chunk.Stderr = string(stderr)
+	chunk.EOF = eof
+	value, err := json.Marshal(chunk)
+	if err != nil {
+		return
+	}
+
+	// 推送到 etcd: /cron/output/任务名称/执行编号/片段序号
+	key := fmt.Sprintf("%s%s/%s/%010d", common.PathOutput, s.State.Task.Name, s.State.ID, s.seq)
+	_, _ = GlobalManager.KV.Put(context.TODO(), key, string(value), clientV3.WithLease(s.LeaseID))
+}
+
+// StreamWriter 任务实时输出写入器
+type StreamWriter struct {
+	stream *Stream
+	stderr bool
+}
+
+// Write 写入任务输出，超出片段上限时只保留最近的输出
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	w.stream.mutex.Lock()
+	defer w.stream.mutex.Unlock()
+
+	buf := &w.stream.stdout
+	if w.stderr {
+		buf = &w.stream.stderr
+	}
+	*buf = append(*buf, p...)
+	if len(*buf) > streamChunkLimit {
+		*buf = append((*buf)[:0:0], (*buf)[len(*buf)-streamChunkLimit:]...)
+	}
+	return len(p), nil
+}
@@ -0,0 +1,279 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/gorhill/cronexpr"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientV3 "go.etcd.io/etcd/client/v3"
+
+	"crontab/common"
+)
+
+// GlobalWorkflow 工作流调度器对象
+var GlobalWorkflow = NewWorkflowScheduler()
+
+// WorkflowPlan 工作流调度计划
+type WorkflowPlan struct {
+	Workflow *common.Workflow     // 工作流信息
+	Expr     *cronexpr.Expression // 解析后的 cron 表达式
+	NextTime time.Time            // 下次调度时间
+}
+
+// WorkflowScheduler 工作流调度器，cron 到期时执行根节点，上游任务结束后触发下游任务
+type WorkflowScheduler struct {
+	PlanTable map[string]*WorkflowPlan // 工作流调度计划表
+	EventChan chan *clientV3.Event     // 工作流变化事件通道
+}
+
+// NewWorkflowScheduler 实例化工作流调度器对象
+func NewWorkflowScheduler() *WorkflowScheduler {
+	return &WorkflowScheduler{
+		PlanTable: make(map[string]*WorkflowPlan),
+		EventChan: make(chan *clientV3.Event, GlobalConfig.ChanSize),
+	}
+}
+
+// Init 初始化工作流调度器对象
+func (w *WorkflowScheduler) Init() error {
+	go w.scheduleLoop()
+	go w.expireLoop()
+
+	// 获取工作流列表
+	resp, err := GlobalManager.KV.Get(context.TODO(), common.PathWorkflow, clientV3.WithPrefix())
+	if err != nil {
+		return err
+	}
+	for _, kv := range resp.Kvs {
+		w.EventChan <- &clientV3.Event{Type: mvccpb.PUT, Kv: kv}
+	}
+
+	// 监听工作流变化事件
+	go func() {
+		watchChan := GlobalManager.Watcher.Watch(context.TODO(), common.PathWorkflow, clientV3.WithPrefix(), clientV3.WithRev(resp.Header.Revision+1))
+		for watchResp := range watchChan {
+			for _, e := range watchResp.Events {
+				w.EventChan <- e
+			}
+		}
+	}()
+
+	return nil
+}
+
+// handleEvent 增删改内存中维护的工作流列表
+func (w *WorkflowScheduler) handleEvent(e *clientV3.Event) {
+	name := common.ExtractName(string(e.Kv.Key), common.PathWorkflow)
+	switch e.Type {
+	case mvccpb.PUT: // 保存工作流事件
+		workflow := common.NewWorkflow()
+		if err := workflow.Unmarshal(e.Kv.Value); err != nil {
+			return
+		}
+
+		// 未配置 cron 表达式的工作流只能手动执行
+		expr, err := cronexpr.Parse(workflow.CronExpr)
+		if err != nil {
+			delete(w.PlanTable, name)
+			return
+		}
+		w.PlanTable[name] = &WorkflowPlan{
+			Workflow: workflow,
+			Expr:     expr,
+			NextTime: expr.Next(time.Now()),
+		}
+	case mvccpb.DELETE: // 删除工作流事件
+		delete(w.PlanTable, name)
+	}
+}
+
+// schedule 计算工作流调度状态
+func (w *WorkflowScheduler) schedule() time.Duration {
+	now := time.Now()
+	duration := 1 * time.Second
+	for _, plan := range w.PlanTable {
+		// 执行到期的工作流，暂停的工作流仅推进调度时间
+		if !plan.NextTime.After(now) {
+			if !plan.Workflow.Disabled {
+				go w.Start(plan.Workflow, plan.NextTime)
+			}
+			plan.NextTime = plan.Expr.Next(now)
+		}
+
+		// 统计最近需要执行的工作流时间
+		if next := plan.NextTime.Sub(now); next < duration {
+			duration = next
+		}
+	}
+	return duration
+}
+
+// scheduleLoop 工作流调度协程
+func (w *WorkflowScheduler) scheduleLoop() {
+	timer := time.NewTimer(1 * time.Second)
+	for {
+		select {
+		case e := <-w.EventChan: // 监听工作流变化事件
+			w.handleEvent(e)
+		case <-timer.C: // 最近需要执行的工作流到期
+		}
+		timer.Reset(w.schedule())
+	}
+}
+
+// Start 创建工作流执行并触发根节点，所有节点以调度时间创建同一个执行，只有一个节点能创建成功
+func (w *WorkflowScheduler) Start(workflow *common.Workflow, planTime time.Time) {
+	// 构建工作流执行
+	run := common.NewWorkflowRun(workflow, planTime)
+	ready := run.Advance()
+	value, err := json.Marshal(run)
+	if err != nil {
+		return
+	}
+
+	// 工作流执行状态随租约过期删除
+	leaseResp, err := GlobalManager.Lease.Grant(context.TODO(), common.WorkflowRunTTL)
+	if err != nil {
+		return
+	}
+
+	// 事务创建工作流执行，已被其他节点创建时撤销租约
+	key := common.PathWorkflowRun + workflow.Name + "/" + run.ID
+	txnResp, err := GlobalManager.KV.Txn(context.TODO()).
+		If(clientV3.Compare(clientV3.CreateRevision(key), "=", 0)).
+		Then(clientV3.OpPut(key, string(value), clientV3.WithLease(leaseResp.ID))).
+		Commit()
+	if err != nil || !txnResp.Succeeded {
+		_, _ = GlobalManager.Lease.Revoke(context.TODO(), leaseResp.ID)
+		return
+	}
+
+	// 触发根节点
+	w.trigger(run, ready)
+}
+
+// Complete 记录工作流任务节点的执行结果，并触发满足条件的下游任务
+func (w *WorkflowScheduler) Complete(result *common.Result) {
+	// 构建任务节点执行结果
+	trigger := result.State.Trigger
+	node := &common.NodeRun{
+		Status:  common.NodeStatus(result.Status),
+		ExecID:  result.State.ID,
+		Worker:  result.Worker,
+		EndTime: result.EndTime.UnixMilli(),
+	}
+	if result.Error != nil {
+		node.Error = result.Error.Error()
+	}
+
+	// 节点已记录执行结果时不再更新
+	key := common.PathWorkflowRun + trigger.Workflow + "/" + trigger.RunID
+	w.update(key, func(run *common.WorkflowRun) bool {
+		return run.Finish(result.State.Task.Name, node)
+	})
+}
+
+// Dispatch 记录认领工作流任务节点的 worker，超时未被认领的节点将被记为执行失败
+func (w *WorkflowScheduler) Dispatch(state *common.State, worker string) {
+	key := common.PathWorkflowRun + state.Trigger.Workflow + "/" + state.Trigger.RunID
+	w.update(key, func(run *common.WorkflowRun) bool {
+		return run.Dispatch(state.Task.Name, state.ID, worker)
+	})
+}
+
+// expireLoop 定期将超时未被认领的工作流任务节点记为执行失败，避免下游节点和工作流执行一直等待
+func (w *WorkflowScheduler) expireLoop() {
+	timeout := time.Duration(GlobalConfig.WorkflowDispatchTimeout) * time.Millisecond
+	if timeout <= 0 {
+		timeout = common.WorkflowDispatchTimeout * time.Millisecond
+	}
+	ticker := time.NewTicker(timeout / 2)
+	for range ticker.C {
+		// 获取工作流执行列表
+		resp, err := GlobalManager.KV.Get(context.TODO(), common.PathWorkflowRun, clientV3.WithPrefix())
+		if err != nil {
+			continue
+		}
+
+		// 只更新存在超时节点的执行中的工作流
+		deadline := time.Now().Add(-timeout).UnixMilli()
+		for _, kv := range resp.Kvs {
+			run := &common.WorkflowRun{}
+			if err := run.Unmarshal(kv.Value); err != nil || run.Status != common.StatusRunning {
+				continue
+			}
+			if len(run.Expire(deadline)) == 0 {
+				continue
+			}
+			w.update(string(kv.Key), func(run *common.WorkflowRun) bool {
+				return len(run.Expire(deadline)) != 0
+			})
+		}
+	}
+}
+
+// update 乐观锁更新工作流执行状态，冲突时重试，modify 返回 false 时不更新，更新后触发可以执行的任务节点
+func (w *WorkflowScheduler) update(key string, modify func(run *common.WorkflowRun) bool) {
+	for i := 0; i < 10; i++ {
+		resp, err := GlobalManager.KV.Get(context.TODO(), key)
+		if err != nil || len(resp.Kvs) == 0 {
+			return
+		}
+		run := &common.WorkflowRun{}
+		if err := run.Unmarshal(resp.Kvs[0].Value); err != nil {
+			return
+		}
+		if !modify(run) {
+			return
+		}
+		ready := run.Advance()
+		value, err := json.Marshal(run)
+		if err != nil {
+			return
+		}
+
+		// 保存工作流执行状态，保留原有租约
+		txnResp, err := GlobalManager.KV.Txn(context.TODO()).
+			If(clientV3.Compare(clientV3.ModRevision(key), "=", resp.Kvs[0].ModRevision)).
+			Then(clientV3.OpPut(key, string(value), clientV3.WithIgnoreLease())).
+			Commit()
+		if err != nil {
+			return
+		}
+		if txnResp.Succeeded {
+			w.trigger(run, ready)
+			return
+		}
+	}
+}
+
+// trigger 通知 worker 服务执行工作流的任务节点
+func (w *WorkflowScheduler) trigger(run *common.WorkflowRun, nodes []string) {
+	if len(nodes) == 0 {
+		return
+	}
+
+	// 序列化触发任务信息
+	trigger := common.NewTrigger()
+	trigger.User = "workflow/" + run.Workflow.Name
+	trigger.Time = time.Now().UnixMilli()
+	trigger.Workflow = run.Workflow.Name
+	trigger.RunID = run.ID
+	value, err := json.Marshal(trigger)
+	if err != nil {
+		return
+	}
+
+	// 创建租约
+	leaseResp, err := GlobalManager.Lease.Grant(context.TODO(), 1)
+	if err != nil {
+		return
+	}
+
+	// 设置手动执行任务标记
+	for _, name := range nodes {
+		_, _ = GlobalManager.KV.Put(context.TODO(), common.PathRun+name, string(value), clientV3.WithLease(leaseResp.ID))
+	}
+}